@@ -1,8 +1,12 @@
 package config
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
 	"math/rand"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,18 +18,170 @@ type DB struct {
 	User     string
 	Password string
 	Timezone *time.Location
+	// Each query is canceled when it passes, 0 is unlimited
+	QueryTimeout time.Duration
+}
+
+// JWT is token signing configuration
+type JWT struct {
+	Algorithm      string
+	SecretKey      string
+	PrivateKeyFile string
+	// Keys only for verifying token signed before rotation
+	VerificationSecretKeys []string
+	VerificationKeyFiles   []string
+	// Registered claims, not issued and validated if empty
+	Issuer   string
+	Audience string
+}
+
+// Lockout is configuration of locking account after repeated failed logins
+type Lockout struct {
+	// Number of failed logins until locking, account is never locked if zero
+	Threshold int
+	// Duration of first locking, it is doubled at every failure after that
+	Duration time.Duration
+}
+
+// RateLimit is limit of requests with token bucket, Burst requests are allowed in Period
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// RateLimits is rate limits for each route, the route is not limited if burst is zero
+type RateLimits struct {
+	Auth     RateLimit
+	Activate RateLimit
+	Register RateLimit
+	// Requesting and resetting password
+	Password   RateLimit
+	MailVerify RateLimit
+	Refresh    RateLimit
+	Introspect RateLimit
+}
+
+// Mail is mail delivery configuration
+type Mail struct {
+	// One of MailDriver* constants
+	Driver string
+	// SMTP server, it is required for MailDriverSMTP
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+	// Directory mail is written to for MailDriverLog, only recipient and subject are logged if empty
+	Dir string
+}
+
+// PasswordHash is configuration of hashing password, hashes made with other algorithm or parameters are rehashed at login
+type PasswordHash struct {
+	// One of PasswordHash* constants
+	Algorithm  string
+	BcryptCost int
+	// Parameters of Argon2id, memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// PasswordPolicy is rules of password set by user, zero value does not restrict anything
+type PasswordPolicy struct {
+	// Length is counted in characters, max length 0 is unlimited
+	MinLength int
+	MaxLength int
+	// Required character classes
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Whether characters other than ASCII are allowed
+	AllowUnicode bool
+	// Whether password containing account or a word of name is rejected
+	RejectUserInfo bool
+	// Whether password in bundled common password list is rejected
+	RejectCommon bool
+	// Number of recent passwords (including current one) not allowed to be reused, 0 allows reuse
+	History int
+	// Password older than it must be changed before login, 0 means password never expires
+	MaxAge time.Duration
 }
 
 // App is application configuration
 type App struct {
 	Debug bool
+	// Who is able to register account, one of Registration* constants
+	Registration string
+	// Issuer displayed in authenticator app for two-factor authentication
+	MFAIssuer string
+	// Whether user is not able to login until the mail address is verified
+	RequireMailVerification bool
+	// Addresses or CIDRs of proxies trusted to set client IP in X-Forwarded-For, remote address is client IP if empty
+	TrustedProxies []string
+	// Whether OAuth 2.0 and OpenID Connect endpoints are served, issuer of JWT is required for them
+	OAuth bool
 	DB
+	JWT
+	Lockout
+	RateLimits
+	Mail
+	PasswordHash
+	PasswordPolicy
 }
 
 const (
-	IdentityKey = "id"
+	IdentityKey     = "id"
+	RoleKey         = "role"
+	TokenVersionKey = "ver"
+	SessionKey      = "sid"
+	// APIKeyPrefix is prefix of personal API key, it distinguishes API key from token in authorization header
+	APIKeyPrefix = "ak_"
+)
+
+const (
+	// RegistrationAdmin is only administrator registers account
+	RegistrationAdmin = "admin"
+	// RegistrationOpen is anyone registers general account
+	RegistrationOpen = "open"
+	// RegistrationInvite is anyone having invitation code registers general account
+	RegistrationInvite = "invite"
+)
+
+const (
+	// MailDriverSMTP is delivering mail with SMTP server
+	MailDriverSMTP = "smtp"
+	// MailDriverLog is writing mail to file instead of delivering, only for local development
+	MailDriverLog = "log"
 )
 
+const (
+	// PasswordHashBcrypt is hashing password with bcrypt
+	PasswordHashBcrypt = "bcrypt"
+	// PasswordHashArgon2id is hashing password with Argon2id
+	PasswordHashArgon2id = "argon2id"
+)
+
+// ValidRegistration is check registration mode is supported
+func (a App) ValidRegistration() bool {
+	switch a.Registration {
+	case RegistrationAdmin, RegistrationOpen, RegistrationInvite:
+		return true
+	}
+	return false
+}
+
+// ValidOAuth is check issuer is configured when OAuth is enabled,
+// it is not decided from request because Host header is able to be forged by client
+func (a App) ValidOAuth() bool {
+	return !a.OAuth || a.JWT.Issuer != ""
+}
+
+// PublicRegistration is check account is registered without authentication
+func (a App) PublicRegistration() bool {
+	return a.Registration == RegistrationOpen || a.Registration == RegistrationInvite
+}
+
 var (
 	// Rand for this package.
 	r *rand.Rand
@@ -46,6 +202,15 @@ func RandomString(l int) string {
 	return v
 }
 
+// RandomToken is generate URL safe string from secure random bytes at specify length
+func RandomToken(l int) (string, error) {
+	b := make([]byte, l)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // GetenvOrDefault is return got value from env or default value
 func GetenvOrDefault(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -53,3 +218,60 @@ func GetenvOrDefault(key, fallback string) string {
 	}
 	return fallback
 }
+
+// GetenvInt is return got integer value from env or default value if not specified or invalid
+func GetenvInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// GetenvBool is return got boolean value (e.g. "true", "0") from env or default value if not specified or invalid
+func GetenvBool(key string, fallback bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// GetenvDuration is return got duration (e.g. "15m") from env or default value if not specified or invalid
+func GetenvDuration(key string, fallback time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// GetenvRateLimit is return got rate limit (e.g. "10/1m") from env or default value if not specified or invalid.
+// "0" disables rate limit.
+func GetenvRateLimit(key string, fallback RateLimit) RateLimit {
+	v := os.Getenv(key)
+	if v == "0" {
+		return RateLimit{}
+	}
+	burst, period, ok := strings.Cut(v, "/")
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.Atoi(burst)
+	if err != nil || b < 0 {
+		return fallback
+	}
+	p, err := time.ParseDuration(period)
+	if err != nil || p <= 0 {
+		return fallback
+	}
+	return RateLimit{Burst: b, Period: p}
+}
+
+// GetenvList is return comma separated values from env
+func GetenvList(key string) []string {
+	var res []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v := strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
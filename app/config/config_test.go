@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,8 +16,79 @@ func TestGenerate(t *testing.T) {
 	}
 }
 
+func TestRandomToken(t *testing.T) {
+	a, err := RandomToken(16)
+	assert.Nil(t, err)
+	assert.Equal(t, 22, len(a))
+
+	b, err := RandomToken(16)
+	assert.Nil(t, err)
+	assert.NotEqual(t, a, b)
+}
+
 func TestGetenvOrDefault(t *testing.T) {
 	os.Setenv("HOGE", "fuga")
 	assert.Equal(t, "fuga", GetenvOrDefault("HOGE", "piyo"))
 	assert.Equal(t, "piyo", GetenvOrDefault("HOGE1", "piyo"))
 }
+
+func TestGetenvInt(t *testing.T) {
+	os.Setenv("HOGE", "10")
+	assert.Equal(t, 10, GetenvInt("HOGE", 5))
+	os.Setenv("HOGE", "fuga")
+	assert.Equal(t, 5, GetenvInt("HOGE", 5))
+	assert.Equal(t, 5, GetenvInt("HOGE1", 5))
+}
+
+func TestGetenvBool(t *testing.T) {
+	os.Setenv("HOGE", "true")
+	assert.True(t, GetenvBool("HOGE", false))
+	os.Setenv("HOGE", "0")
+	assert.False(t, GetenvBool("HOGE", true))
+	os.Setenv("HOGE", "fuga")
+	assert.True(t, GetenvBool("HOGE", true))
+	assert.False(t, GetenvBool("HOGE1", false))
+}
+
+func TestGetenvDuration(t *testing.T) {
+	os.Setenv("HOGE", "30m")
+	assert.Equal(t, 30*time.Minute, GetenvDuration("HOGE", time.Minute))
+	os.Setenv("HOGE", "30")
+	assert.Equal(t, time.Minute, GetenvDuration("HOGE", time.Minute))
+	assert.Equal(t, time.Minute, GetenvDuration("HOGE1", time.Minute))
+}
+
+func TestGetenvRateLimit(t *testing.T) {
+	fallback := RateLimit{Burst: 5, Period: time.Minute}
+
+	os.Setenv("HOGE", "10/1h")
+	assert.Equal(t, RateLimit{Burst: 10, Period: time.Hour}, GetenvRateLimit("HOGE", fallback))
+	os.Setenv("HOGE", "0")
+	assert.Equal(t, RateLimit{}, GetenvRateLimit("HOGE", fallback))
+	for _, v := range []string{"10", "a/1m", "10/1", "10/0s", "-1/1m"} {
+		os.Setenv("HOGE", v)
+		assert.Equal(t, fallback, GetenvRateLimit("HOGE", fallback))
+	}
+	assert.Equal(t, fallback, GetenvRateLimit("HOGE1", fallback))
+}
+
+func TestGetenvList(t *testing.T) {
+	os.Setenv("HOGE", "fuga, piyo,,")
+	assert.Equal(t, []string{"fuga", "piyo"}, GetenvList("HOGE"))
+	assert.Empty(t, GetenvList("HOGE1"))
+}
+
+func TestRegistration(t *testing.T) {
+	assert.True(t, App{Registration: RegistrationAdmin}.ValidRegistration())
+	assert.False(t, App{Registration: RegistrationAdmin}.PublicRegistration())
+	assert.True(t, App{Registration: RegistrationOpen}.PublicRegistration())
+	assert.True(t, App{Registration: RegistrationInvite}.PublicRegistration())
+	assert.False(t, App{Registration: "unknown"}.ValidRegistration())
+	assert.False(t, App{}.ValidRegistration())
+}
+
+func TestOAuth(t *testing.T) {
+	assert.True(t, App{}.ValidOAuth())
+	assert.False(t, App{OAuth: true}.ValidOAuth())
+	assert.True(t, App{OAuth: true, JWT: JWT{Issuer: "https://auth.example.com"}}.ValidOAuth())
+}
@@ -0,0 +1,8 @@
+package entity
+
+// Mail is struct of mail sent to user
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
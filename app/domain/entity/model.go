@@ -3,6 +3,8 @@ package entity
 import (
 	"database/sql/driver"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,6 +21,16 @@ const (
 	GenderUnknown = Gender("Unknown")
 )
 
+// Scopes of the API granted to personal API key and OAuth client,
+// token issued by login of the user is not limited by scope.
+const (
+	ScopeProfile = "profile"
+	ScopeAdmin   = "admin"
+)
+
+// APIScopes is scopes of the API which personal API key is able to be granted
+var APIScopes = []string{ScopeProfile, ScopeAdmin}
+
 type Date struct {
 	time.Time
 }
@@ -44,18 +56,37 @@ func (d Date) Value() (driver.Value, error) {
 
 // User is struct of authenticated user data
 type User struct {
-	ID          uint       `gorm:"primary_key" json:"id"`
-	Account     string     `gorm:"type:varchar(20);not null;unique_index" json:"account"`
-	Name        string     `gorm:"type:varchar(50);not null" json:"name"`
-	Password    string     `gorm:"type:varchar(255);not null" json:"-"`
-	Gender      Gender     `gorm:"type:enum('Male','Female','Unknown');not null" json:"gender"`
-	MailAddress string     `gorm:"type:varchar(255);not null" json:"mailAddress"`
-	Birthday    Date       `gorm:"type:date;not null" json:"birthday"`
-	Role        Role       `gorm:"type:enum('Administrator','General');not null"`
-	LastLogged  *time.Time `gorm:"type:datetime" json:"-"`
-	IsActive    bool       `gorm:"type:tinyint;not null" json:"-"`
-	IsEnable    bool       `gorm:"type:tinyint;not null" json:"-"`
-	CreatedAt   time.Time  `gorm:"type:datetime;not null" sql:"default:current_timestamp" json:"-"`
+	ID          uint   `gorm:"primary_key" json:"id"`
+	Account     string `gorm:"type:varchar(20);not null;unique_index" json:"account"`
+	Name        string `gorm:"type:varchar(50);not null" json:"name"`
+	Password    string `gorm:"type:varchar(255);not null" json:"-"`
+	Gender      Gender `gorm:"type:enum('Male','Female','Unknown');not null" json:"gender"`
+	MailAddress string `gorm:"type:varchar(255);not null" json:"mailAddress"`
+	// Time when the mail address is verified, it is cleared when the mail address is changed
+	MailVerifiedAt *time.Time `gorm:"type:datetime" json:"mailVerifiedAt"`
+	Birthday       Date       `gorm:"type:date;not null" json:"birthday"`
+	Role           Role       `gorm:"type:enum('Administrator','General');not null"`
+	LastLogged     *time.Time `gorm:"type:datetime" json:"-"`
+	// Time when the password is changed, it is empty if the user is created before password age is tracked
+	PasswordChangedAt *time.Time `gorm:"type:datetime" json:"-"`
+	IsActive          bool       `gorm:"type:tinyint;not null" json:"-"`
+	IsEnable          bool       `gorm:"type:tinyint;not null" json:"-"`
+	TokenVersion      uint       `gorm:"not null;default:0" json:"-"`
+	FailedLogins      uint       `gorm:"not null;default:0" json:"-"`
+	LockedUntil       *time.Time `gorm:"type:datetime" json:"-"`
+	MFASecret         string     `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	MFAEnabled        bool       `gorm:"type:tinyint;not null;default:0" json:"-"`
+	MFAUsedStep       int64      `gorm:"not null;default:0" json:"-"`
+	CreatedAt         time.Time  `gorm:"type:datetime;not null" sql:"default:current_timestamp" json:"-"`
+}
+
+// UserFilter is condition for finding users
+type UserFilter struct {
+	Role     *Role
+	IsEnable *bool
+	IsActive *bool
+	Offset   int
+	Limit    int
 }
 
 // Valid is valid user data
@@ -63,7 +94,289 @@ func (u *User) Valid() bool {
 	return u.Account != "" && u.IsEnable
 }
 
+// Locked is whether account is locked by failed logins at the time
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
+// MailVerified is whether the current mail address is verified
+func (u *User) MailVerified() bool {
+	return u.MailVerifiedAt != nil
+}
+
+// PasswordExpired is whether the password is older than max age at the time, max age 0 means password never expires.
+// Password never changed is as old as the user, so that it also expires.
+func (u *User) PasswordExpired(maxAge time.Duration, now time.Time) bool {
+	changed := u.CreatedAt
+	if u.PasswordChangedAt != nil {
+		changed = *u.PasswordChangedAt
+	}
+	return maxAge > 0 && !changed.IsZero() && !changed.Add(maxAge).After(now)
+}
+
+// ChangeMailAddress is change mail address, the address becomes unverified if it is changed
+func (u *User) ChangeMailAddress(mailAddress string) {
+	if u.MailAddress == mailAddress {
+		return
+	}
+	u.MailAddress = mailAddress
+	u.MailVerifiedAt = nil
+}
+
+// ResetMFA is disable two-factor authentication and remove the secret
+func (u *User) ResetMFA() {
+	u.MFASecret = ""
+	u.MFAEnabled = false
+	u.MFAUsedStep = 0
+}
+
 // DefaultRole is get user default role
 func (u *User) DefaultRole() Role {
 	return RoleGeneral
 }
+
+// Managed is get user data for administrator
+func (u *User) Managed() ManagedUser {
+	return ManagedUser{
+		User:              *u,
+		LastLogged:        u.LastLogged,
+		PasswordChangedAt: u.PasswordChangedAt,
+		IsActive:          u.IsActive,
+		IsEnable:          u.IsEnable,
+		CreatedAt:         u.CreatedAt,
+		FailedLogins:      u.FailedLogins,
+		LockedUntil:       u.LockedUntil,
+		MFAEnabled:        u.MFAEnabled,
+	}
+}
+
+// UserInfo is get standard claims of OpenID Connect, only claims of profile and email scopes in the scopes are included
+func (u *User) UserInfo(scopes []string) UserInfo {
+	info := UserInfo{
+		Sub: strconv.FormatUint(uint64(u.ID), 10),
+	}
+	for _, s := range scopes {
+		switch s {
+		case "profile":
+			info.Name = u.Name
+			info.PreferredUsername = u.Account
+			if u.Gender != GenderUnknown {
+				info.Gender = strings.ToLower(string(u.Gender))
+			}
+			if !u.Birthday.IsZero() {
+				info.Birthdate = u.Birthday.Format("2006-01-02")
+			}
+		case "email":
+			info.Email = u.MailAddress
+		}
+	}
+	return info
+}
+
+// RevokedToken is struct of token revoked before expiration
+type RevokedToken struct {
+	ID        uint      `gorm:"primary_key"`
+	TokenID   string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt time.Time `gorm:"type:datetime;not null;index"`
+	CreatedAt time.Time `gorm:"type:datetime;not null"`
+}
+
+// RefreshToken is struct of opaque token for reissuing access token
+type RefreshToken struct {
+	ID        uint       `gorm:"primary_key"`
+	UserID    uint       `gorm:"not null;index"`
+	FamilyID  string     `gorm:"type:varchar(64);not null;index"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt time.Time  `gorm:"type:datetime;not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	RevokedAt *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"type:datetime;not null"`
+}
+
+// Session is struct of signed in session, it is continued by rotating refresh tokens of the family
+type Session struct {
+	ID       uint   `gorm:"primary_key" json:"id"`
+	UserID   uint   `gorm:"not null;index" json:"-"`
+	FamilyID string `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	TokenID  string `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	// OAuth client which the session is authorized for, it is empty for login of the user
+	ClientID   string     `gorm:"type:varchar(64);not null;default:''" json:"clientId,omitempty"`
+	Scope      string     `gorm:"type:varchar(255);not null;default:''" json:"scope,omitempty"`
+	IPAddress  string     `gorm:"type:varchar(45);not null;default:''" json:"ipAddress"`
+	UserAgent  string     `gorm:"type:varchar(255);not null;default:''" json:"userAgent"`
+	LastSeenAt *time.Time `gorm:"type:datetime" json:"lastSeenAt"`
+	ExpiredAt  time.Time  `gorm:"type:datetime;not null" json:"expiredAt"`
+	RevokedAt  *time.Time `gorm:"type:datetime" json:"-"`
+	CreatedAt  time.Time  `gorm:"type:datetime;not null" json:"createdAt"`
+}
+
+// Active is whether session is neither revoked nor expired at the time
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiredAt.After(now)
+}
+
+// Invitation is struct of single-use code for registering account
+type Invitation struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	CreatedBy uint       `gorm:"not null" json:"createdBy"`
+	ExpiredAt time.Time  `gorm:"type:datetime;not null" json:"expiredAt"`
+	UsedAt    *time.Time `gorm:"type:datetime" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"type:datetime;not null" json:"createdAt"`
+}
+
+// PasswordReset is struct of single-use token for resetting forgotten password
+type PasswordReset struct {
+	ID        uint       `gorm:"primary_key"`
+	UserID    uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt time.Time  `gorm:"type:datetime;not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"type:datetime;not null"`
+}
+
+// PasswordHistory is struct of hash of password used by user before, it prevents reusing recent passwords
+type PasswordHistory struct {
+	ID        uint      `gorm:"primary_key"`
+	UserID    uint      `gorm:"not null;index"`
+	Password  string    `gorm:"type:varchar(255);not null"`
+	CreatedAt time.Time `gorm:"type:datetime;not null"`
+}
+
+// MailVerification is struct of single-use token for verifying mail address of user.
+// The token is valid only while the user has the same mail address.
+type MailVerification struct {
+	ID          uint       `gorm:"primary_key"`
+	UserID      uint       `gorm:"not null;index"`
+	MailAddress string     `gorm:"type:varchar(255);not null"`
+	TokenHash   string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt   time.Time  `gorm:"type:datetime;not null"`
+	UsedAt      *time.Time `gorm:"type:datetime"`
+	CreatedAt   time.Time  `gorm:"type:datetime;not null"`
+}
+
+// RecoveryCode is struct of one-time code for authenticating without second factor
+type RecoveryCode struct {
+	ID        uint       `gorm:"primary_key"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"type:datetime;not null"`
+}
+
+// Client is struct of OAuth client registered for obtaining token.
+// Public client such as SPA and mobile app can not keep secret, so it is allowed only authorization code with PKCE.
+type Client struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	ClientID     string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"clientId"`
+	SecretHash   string    `gorm:"type:varchar(64);not null" json:"-"`
+	Name         string    `gorm:"type:varchar(50);not null" json:"name"`
+	Scope        string    `gorm:"type:varchar(255);not null;default:''" json:"scope"`
+	Public       bool      `gorm:"type:tinyint;not null;default:0" json:"public"`
+	RedirectURIs []string  `gorm:"type:text;serializer:json" json:"redirectUris"`
+	IsEnable     bool      `gorm:"type:tinyint;not null;default:1" json:"isEnable"`
+	CreatedAt    time.Time `gorm:"type:datetime;not null" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"type:datetime;not null" json:"updatedAt"`
+}
+
+// Scopes is return space-delimited scope of client as list
+func (c *Client) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// RedirectURI is return registered redirect URI matching exactly to the URI.
+// Only one registered URI is used if the URI is empty.
+func (c *Client) RedirectURI(uri string) (string, bool) {
+	if uri == "" {
+		if len(c.RedirectURIs) == 1 {
+			return c.RedirectURIs[0], true
+		}
+		return "", false
+	}
+	for _, v := range c.RedirectURIs {
+		if v == uri {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// AuthorizationCode is struct of single-use code issued by authorization endpoint of OAuth
+type AuthorizationCode struct {
+	ID            uint       `gorm:"primary_key"`
+	CodeHash      string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ClientID      string     `gorm:"type:varchar(64);not null"`
+	UserID        uint       `gorm:"not null;index"`
+	RedirectURI   string     `gorm:"type:text;not null"`
+	Scope         string     `gorm:"type:varchar(255);not null;default:''"`
+	CodeChallenge string     `gorm:"type:varchar(128);not null"`
+	Nonce         string     `gorm:"type:varchar(255);not null;default:''"`
+	ExpiredAt     time.Time  `gorm:"type:datetime;not null"`
+	UsedAt        *time.Time `gorm:"type:datetime"`
+	CreatedAt     time.Time  `gorm:"type:datetime;not null"`
+}
+
+// APIKey is struct of personal API key for machine access of the user, the key is stored as hash
+type APIKey struct {
+	ID      uint   `gorm:"primary_key" json:"id"`
+	UserID  uint   `gorm:"not null;index" json:"-"`
+	Name    string `gorm:"type:varchar(50);not null" json:"name"`
+	Prefix  string `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash string `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scope   string `gorm:"type:varchar(255);not null;default:''" json:"scope"`
+	// Token version of the user at issuing, the key is revoked when the version is incremented
+	TokenVersion uint       `gorm:"not null;default:0" json:"-"`
+	ExpiredAt    *time.Time `gorm:"type:datetime" json:"expiredAt"`
+	LastUsedAt   *time.Time `gorm:"type:datetime" json:"lastUsedAt"`
+	CreatedAt    time.Time  `gorm:"type:datetime;not null" json:"createdAt"`
+}
+
+// Expired is whether API key is expired at the time, the key without expiration is never expired
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiredAt != nil && !k.ExpiredAt.After(now)
+}
+
+// AuditEventType is type of security-relevant event
+type AuditEventType string
+
+const (
+	AuditLoginSucceeded = AuditEventType("login.succeeded")
+	AuditLoginFailed    = AuditEventType("login.failed")
+	AuditLogout         = AuditEventType("logout")
+	AuditRegistered     = AuditEventType("user.registered")
+	AuditActivated      = AuditEventType("user.activated")
+	AuditPasswordChange = AuditEventType("password.changed")
+	AuditRoleChange     = AuditEventType("role.changed")
+	AuditPasswordExpire = AuditEventType("password.expired")
+	AuditEnabled        = AuditEventType("user.enabled")
+	AuditDisabled       = AuditEventType("user.disabled")
+	AuditUnlocked       = AuditEventType("user.unlocked")
+	AuditMFAReset       = AuditEventType("mfa.reset")
+	AuditDeleted        = AuditEventType("user.deleted")
+)
+
+// AuditEvent is struct of security-relevant event, it is only appended and never updated.
+// Actor is the user who operated, and target is the user who was operated.
+type AuditEvent struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	Type      AuditEventType `gorm:"type:varchar(50);not null;index" json:"type"`
+	ActorID   *uint          `gorm:"index" json:"actorId"`
+	TargetID  *uint          `gorm:"index" json:"targetId"`
+	Account   string         `gorm:"type:varchar(20);not null;default:''" json:"account"`
+	Detail    string         `gorm:"type:varchar(255);not null;default:''" json:"detail"`
+	IPAddress string         `gorm:"type:varchar(45);not null" json:"ipAddress"`
+	UserAgent string         `gorm:"type:varchar(255);not null" json:"userAgent"`
+	CreatedAt time.Time      `gorm:"type:datetime;not null;index" json:"createdAt"`
+}
+
+// AuditFilter is condition for finding audit events, events older than cursor are found in descending order
+type AuditFilter struct {
+	Type     *AuditEventType
+	Account  *string
+	ActorID  *uint
+	TargetID *uint
+	Since    *time.Time
+	Until    *time.Time
+	Cursor   uint
+	Limit    int
+}
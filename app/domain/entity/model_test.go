@@ -48,7 +48,114 @@ func TestValidUser(t *testing.T) {
 	assert.True(t, u.Valid())
 }
 
+func TestLockedUser(t *testing.T) {
+	now := time.Now()
+	u := User{}
+	assert.False(t, u.Locked(now))
+	until := now.Add(time.Minute)
+	u.LockedUntil = &until
+	assert.True(t, u.Locked(now))
+	assert.False(t, u.Locked(until))
+}
+
+func TestPasswordExpired(t *testing.T) {
+	now := time.Now()
+	u := User{}
+	assert.False(t, u.PasswordExpired(time.Hour, now))
+
+	// password never changed is as old as the user
+	u.CreatedAt = now.Add(-2 * time.Hour)
+	assert.False(t, u.PasswordExpired(0, now))
+	assert.False(t, u.PasswordExpired(3*time.Hour, now))
+	assert.True(t, u.PasswordExpired(time.Hour, now))
+
+	changed := now.Add(-time.Hour)
+	u.PasswordChangedAt = &changed
+	assert.False(t, u.PasswordExpired(0, now))
+	assert.False(t, u.PasswordExpired(2*time.Hour, now))
+	assert.True(t, u.PasswordExpired(time.Hour, now))
+}
+
+func TestChangeMailAddress(t *testing.T) {
+	now := time.Now()
+	u := User{MailAddress: "test@example.com", MailVerifiedAt: &now}
+	assert.True(t, u.MailVerified())
+
+	u.ChangeMailAddress("test@example.com")
+	assert.True(t, u.MailVerified())
+
+	u.ChangeMailAddress("changed@example.com")
+	assert.Equal(t, "changed@example.com", u.MailAddress)
+	assert.False(t, u.MailVerified())
+}
+
 func TestUserDefaultRole(t *testing.T) {
 	u := User{}
 	assert.Equal(t, u.DefaultRole(), RoleGeneral)
 }
+
+func TestUserInfo(t *testing.T) {
+	birthday, _ := time.Parse("2006-01-02", "2000-01-31")
+	u := User{
+		ID:          1,
+		Account:     "testuser",
+		Name:        "Test User",
+		Gender:      GenderFemale,
+		MailAddress: "test@example.com",
+		Birthday:    Date{Time: birthday},
+	}
+
+	assert.Equal(t, UserInfo{Sub: "1"}, u.UserInfo([]string{"openid"}))
+	assert.Equal(t, UserInfo{
+		Sub:               "1",
+		Name:              "Test User",
+		PreferredUsername: "testuser",
+		Gender:            "female",
+		Birthdate:         "2000-01-31",
+		Email:             "test@example.com",
+	}, u.UserInfo([]string{"openid", "profile", "email"}))
+
+	u.Gender = GenderUnknown
+	assert.Empty(t, u.UserInfo([]string{"profile"}).Gender)
+}
+
+func TestClientRedirectURI(t *testing.T) {
+	c := Client{RedirectURIs: []string{"https://example.com/callback"}}
+
+	uri, ok := c.RedirectURI("")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/callback", uri)
+
+	_, ok = c.RedirectURI("https://example.com/callback/")
+	assert.False(t, ok)
+
+	// not determined if multiple URIs are registered
+	c.RedirectURIs = append(c.RedirectURIs, "app://callback")
+	_, ok = c.RedirectURI("")
+	assert.False(t, ok)
+
+	uri, ok = c.RedirectURI("app://callback")
+	assert.True(t, ok)
+	assert.Equal(t, "app://callback", uri)
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	now := time.Now()
+	k := APIKey{}
+	assert.False(t, k.Expired(now))
+
+	expiredAt := now.Add(time.Hour)
+	k.ExpiredAt = &expiredAt
+	assert.False(t, k.Expired(now))
+	assert.True(t, k.Expired(expiredAt))
+}
+
+func TestSessionActive(t *testing.T) {
+	now := time.Now()
+	s := Session{ExpiredAt: now.Add(time.Hour)}
+	assert.True(t, s.Active(now))
+	assert.False(t, s.Active(s.ExpiredAt))
+
+	s.RevokedAt = &now
+	assert.False(t, s.Active(now))
+}
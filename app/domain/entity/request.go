@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // RegistrationUser is struct of request data for registration user
 type RegistrationUser struct {
 	Account     string  `json:"account" binding:"required,min=8,max=20"`
@@ -8,16 +10,165 @@ type RegistrationUser struct {
 	MailAddress string  `json:"mailAddress" binding:"required,email"`
 	Birthday    string  `json:"birthday" binding:"required,date"`
 	Role        *string `json:"role" binding:"omitempty,oneof=Administrator General"`
+	InviteCode  string  `json:"inviteCode"`
 }
 
-// Activate is validation struct of using during activate user
+// Activate is validation struct of using during activate user,
+// code of authenticator app or recovery code is required if the user enabled two-factor authentication
 type Activate struct {
 	Authenticate
-	NewPassword string `json:"newPassword" binding:"required,password"`
+	NewPassword string `json:"newPassword" binding:"required"`
+	Code        string `json:"code"`
+}
+
+// ChangePassword is validation struct of using during changing password
+type ChangePassword struct {
+	Password    string `json:"password" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// ForgotPassword is validation struct of requesting password reset
+type ForgotPassword struct {
+	Account     string `json:"account" binding:"required_without=MailAddress,omitempty,min=8,max=20"`
+	MailAddress string `json:"mailAddress" binding:"required_without=Account,omitempty,email"`
+}
+
+// ResetPassword is validation struct of resetting password with reset token
+type ResetPassword struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// VerifyMail is validation struct of mail verification token
+type VerifyMail struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerification is validation struct of requesting mail verification token again
+type ResendVerification struct {
+	Account     string `json:"account" binding:"required_without=MailAddress,omitempty,min=8,max=20"`
+	MailAddress string `json:"mailAddress" binding:"required_without=Account,omitempty,email"`
+}
+
+// VerifyMFA is validation struct of code generated by authenticator app
+type VerifyMFA struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+// AuthenticateMFA is validation struct of second factor for challenge, either code or recovery code is required
+type AuthenticateMFA struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode   string `json:"recoveryCode" binding:"required_without=Code"`
 }
 
 // Authenticate is validation struct of using during authentication
 type Authenticate struct {
 	Account  string `json:"account" binding:"required,min=8,max=20"`
-	Password string `json:"password" binding:"required,password"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Refresh is validation struct of using during reissuing token
+type Refresh struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// SearchUser is validation struct of query for searching users
+type SearchUser struct {
+	Page    int     `form:"page" json:"page" binding:"omitempty,min=1"`
+	Limit   int     `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+	Role    *string `form:"role" json:"role" binding:"omitempty,oneof=Administrator General"`
+	Enabled *bool   `form:"enabled" json:"enabled"`
+	Active  *bool   `form:"active" json:"active"`
+}
+
+// SearchAudit is validation struct of query for finding audit events.
+// Cursor is ID of the last event in previous page.
+type SearchAudit struct {
+	Cursor   uint       `form:"cursor" json:"cursor"`
+	Limit    int        `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+	Type     *string    `form:"type" json:"type" binding:"omitempty,oneof=login.succeeded login.failed logout user.registered user.activated password.changed role.changed password.expired user.enabled user.disabled user.unlocked mfa.reset user.deleted"`
+	Account  *string    `form:"account" json:"account" binding:"omitempty,max=20"`
+	ActorID  *uint      `form:"actorId" json:"actorId"`
+	TargetID *uint      `form:"targetId" json:"targetId"`
+	Since    *time.Time `form:"since" json:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until    *time.Time `form:"until" json:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// UpdateUser is validation struct of partial updating user
+type UpdateUser struct {
+	Name        *string `json:"name" binding:"omitempty,max=50"`
+	Gender      *string `json:"gender" binding:"omitempty,oneof=Male Female Unknown"`
+	MailAddress *string `json:"mailAddress" binding:"omitempty,email"`
+	Birthday    *string `json:"birthday" binding:"omitempty,date"`
+	Role        *string `json:"role" binding:"omitempty,oneof=Administrator General"`
+}
+
+// CreateInvitation is validation struct of issuing invitation code
+type CreateInvitation struct {
+	ValidDays int `json:"validDays" binding:"omitempty,min=1,max=90"`
+}
+
+// CreateAPIKey is validation struct of issuing personal API key, the key never expires if valid days is empty.
+// Scope is space-delimited scopes of the API.
+type CreateAPIKey struct {
+	Name      string `json:"name" binding:"required,max=50"`
+	Scope     string `json:"scope" binding:"required,max=255,api_scope"`
+	ValidDays int    `json:"validDays" binding:"omitempty,min=1,max=365"`
+}
+
+// CreateClient is validation struct of registering OAuth client
+type CreateClient struct {
+	Name         string   `json:"name" binding:"required,max=50"`
+	Scope        string   `json:"scope" binding:"omitempty,max=255,scope"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirectUris" binding:"omitempty,max=10,dive,max=255,redirect_uri"`
+}
+
+// UpdateClient is validation struct of partial updating OAuth client
+type UpdateClient struct {
+	Name         *string   `json:"name" binding:"omitempty,min=1,max=50"`
+	Scope        *string   `json:"scope" binding:"omitempty,max=255,scope"`
+	RedirectURIs *[]string `json:"redirectUris" binding:"omitempty,max=10,dive,max=255,redirect_uri"`
+	IsEnable     *bool     `json:"isEnable"`
+}
+
+// Authorize is struct of authorization request of OAuth (RFC 6749, RFC 7636) and OpenID Connect
+type Authorize struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+// Consent is struct of submitted login and consent form of authorization endpoint
+type Consent struct {
+	Authorize
+	Account  string `form:"account"`
+	Password string `form:"password"`
+	Code     string `form:"code"`
+	Approve  bool   `form:"approve"`
+}
+
+// Token is validation struct of token request of OAuth (RFC 6749)
+type Token struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+// Introspect is validation struct of token introspection request (RFC 7662)
+type Introspect struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
 }
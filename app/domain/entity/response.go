@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // Error is struct of error object
 type Error struct {
 	Code    int   `json:"code"`
@@ -22,6 +24,160 @@ type GeneratedPassword struct {
 
 // Claim is struct of logged in user claim data
 type Claim struct {
-	Expire string `json:"expire"`
-	Token  string `json:"token"`
+	Expire       string `json:"expire"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// JWK is struct of JSON Web Key for public key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is struct of JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ManagedUser is struct of user data for administrator
+type ManagedUser struct {
+	User
+	LastLogged        *time.Time `json:"lastLogged"`
+	PasswordChangedAt *time.Time `json:"passwordChangedAt"`
+	IsActive          bool       `json:"isActive"`
+	IsEnable          bool       `json:"isEnable"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	FailedLogins      uint       `json:"failedLogins"`
+	LockedUntil       *time.Time `json:"lockedUntil"`
+	MFAEnabled        bool       `json:"mfaEnabled"`
+}
+
+// Users is struct of paginated users
+type Users struct {
+	Items []ManagedUser `json:"items"`
+	Total int64         `json:"total"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+}
+
+// AuditEvents is struct of audit events in a page, next cursor is empty if it is the last page
+type AuditEvents struct {
+	Items      []AuditEvent `json:"items"`
+	NextCursor uint         `json:"nextCursor,omitempty"`
+}
+
+// UserSession is struct of session of the user, current is whether the request is made in the session
+type UserSession struct {
+	Session
+	Current bool `json:"current"`
+}
+
+// IssuedInvitation is struct of invitation with issued code
+type IssuedInvitation struct {
+	Invitation
+	Code string `json:"code"`
+}
+
+// IssuedAPIKey is struct of API key with issued key
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// MFAChallenge is struct of challenge for second factor after password is verified
+type MFAChallenge struct {
+	MFARequired    bool   `json:"mfaRequired"`
+	ChallengeToken string `json:"challengeToken"`
+	Expire         string `json:"expire"`
+}
+
+// PasswordChangeRequired is struct of error at login of the user who must change password,
+// the token is only accepted for changing password with /v1/me/password
+type PasswordChangeRequired struct {
+	Error
+	PasswordChangeToken string `json:"passwordChangeToken"`
+	Expire              string `json:"expire"`
+}
+
+// MFAEnrollment is struct of secret for registering to authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes is struct of issued recovery codes
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// IssuedClient is struct of OAuth client with issued secret
+type IssuedClient struct {
+	Client
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// OAuthToken is struct of successful token response of OAuth (RFC 6749)
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OpenIDConfiguration is struct of OpenID Provider metadata (OpenID Connect Discovery 1.0)
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfo is struct of standard claims of the user, only claims for granted scopes are included
+type UserInfo struct {
+	Sub               string `json:"sub"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Gender            string `json:"gender,omitempty"`
+	Birthdate         string `json:"birthdate,omitempty"`
+	Email             string `json:"email,omitempty"`
+}
+
+// Introspection is struct of token introspection response (RFC 7662), only active is returned for inactive token
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// OAuthError is struct of error response of OAuth (RFC 6749)
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// APIKey is repository for operate about personal API key, queries are canceled with the context.
+type APIKey interface {
+	Create(ctx context.Context, k *entity.APIKey) (string, error)
+	Find(ctx context.Context, id uint) (*entity.APIKey, error)
+	FindAll(ctx context.Context, userID uint) ([]entity.APIKey, error)
+	FindByKey(ctx context.Context, key string) (*entity.APIKey, error)
+	Touch(ctx context.Context, k *entity.APIKey) error
+	Delete(ctx context.Context, k *entity.APIKey) error
+}
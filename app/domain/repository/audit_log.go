@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// AuditLog is repository for operate about audit events, events are only appended, queries are canceled with the context.
+type AuditLog interface {
+	Create(ctx context.Context, e *entity.AuditEvent) error
+	FindAll(ctx context.Context, f entity.AuditFilter) ([]entity.AuditEvent, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// AuthorizationCode is repository for operate about authorization code of OAuth, queries are canceled with the context.
+type AuthorizationCode interface {
+	Create(ctx context.Context, ac *entity.AuthorizationCode) (string, error)
+	FindByCode(ctx context.Context, code string) (*entity.AuthorizationCode, error)
+	Use(ctx context.Context, ac *entity.AuthorizationCode) (bool, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// Client is repository for operate about OAuth client, queries are canceled with the context.
+type Client interface {
+	Create(ctx context.Context, c *entity.Client) (string, error)
+	Find(ctx context.Context, id uint) (*entity.Client, error)
+	FindByClientID(ctx context.Context, clientID string) (*entity.Client, error)
+	FindAll(ctx context.Context) ([]entity.Client, error)
+	MatchSecret(ctx context.Context, hashedSecret, secret string) error
+	RegenerateSecret(ctx context.Context, c *entity.Client) (string, error)
+	Update(ctx context.Context, c *entity.Client) error
+	Delete(ctx context.Context, c *entity.Client) error
+}
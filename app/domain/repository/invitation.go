@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// Invitation is repository for operate about invitation code, queries are canceled with the context.
+type Invitation interface {
+	Create(ctx context.Context, i *entity.Invitation) (string, error)
+	Find(ctx context.Context, id uint) (*entity.Invitation, error)
+	FindAll(ctx context.Context) ([]entity.Invitation, error)
+	Use(ctx context.Context, code string) (bool, error)
+	Delete(ctx context.Context, i *entity.Invitation) error
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// MailVerification is repository for operate about mail verification token, queries are canceled with the context.
+type MailVerification interface {
+	Create(ctx context.Context, v *entity.MailVerification) (string, error)
+	FindByToken(ctx context.Context, token string) (*entity.MailVerification, error)
+	Use(ctx context.Context, v *entity.MailVerification) (bool, error)
+}
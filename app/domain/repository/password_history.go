@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// PasswordHistory is repository for operate about previous passwords of user, queries are canceled with the context.
+// Histories are kept by User.UpdatePassword.
+type PasswordHistory interface {
+	FindRecent(ctx context.Context, userID uint, limit int) ([]entity.PasswordHistory, error)
+}
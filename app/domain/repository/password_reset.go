@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// PasswordReset is repository for operate about password reset token, queries are canceled with the context.
+type PasswordReset interface {
+	Create(ctx context.Context, r *entity.PasswordReset) (string, error)
+	FindByToken(ctx context.Context, token string) (*entity.PasswordReset, error)
+	Use(ctx context.Context, r *entity.PasswordReset) (bool, error)
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "context"
+
+// RecoveryCode is repository for operate about recovery code of two-factor authentication, queries are canceled with the context.
+type RecoveryCode interface {
+	Create(ctx context.Context, userID uint, n int) ([]string, error)
+	Use(ctx context.Context, userID uint, code string) (bool, error)
+	Delete(ctx context.Context, userID uint) error
+}
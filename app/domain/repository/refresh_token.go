@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// RefreshToken is repository for operate about refresh token, queries are canceled with the context.
+type RefreshToken interface {
+	Create(ctx context.Context, t *entity.RefreshToken) (string, error)
+	FindByToken(ctx context.Context, token string) (*entity.RefreshToken, error)
+	Use(ctx context.Context, t *entity.RefreshToken) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"time"
+)
+
+// RevokedToken is repository for operate about revoked token, queries are canceled with the context.
+type RevokedToken interface {
+	Revoke(ctx context.Context, tokenID string, expiredAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// Session is repository for operate about signed in session, queries are canceled with the context.
+type Session interface {
+	Create(ctx context.Context, s *entity.Session) error
+	Find(ctx context.Context, id uint) (*entity.Session, error)
+	FindByFamily(ctx context.Context, familyID string) (*entity.Session, error)
+	FindAll(ctx context.Context, userID uint) ([]entity.Session, error)
+	Update(ctx context.Context, s *entity.Session) error
+	Touch(ctx context.Context, id uint) error
+	Revoke(ctx context.Context, s *entity.Session) error
+	RevokeUser(ctx context.Context, userID uint) error
+}
@@ -1,16 +1,28 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
 )
 
-// User is repository for operate about user.
+// User is repository for operate about user, queries are canceled with the context.
 type User interface {
-	Exists(account string) (bool, error)
-	Find(id uint) (*entity.User, error)
-	FindByAccount(account string) (*entity.User, error)
-	MatchPassword(hashedPassword, password string) error
-	Create(u *entity.User) (string, error)
-	UpdatePassword(u *entity.User, pass string) error
-	UpdateAuthed(u *entity.User) error
+	Exists(ctx context.Context, account string) (bool, error)
+	Find(ctx context.Context, id uint) (*entity.User, error)
+	FindAll(ctx context.Context, f entity.UserFilter) ([]entity.User, int64, error)
+	FindByAccount(ctx context.Context, account string) (*entity.User, error)
+	FindByMailAddress(ctx context.Context, mailAddress string) ([]entity.User, error)
+	MatchPassword(ctx context.Context, hashedPassword, password string) error
+	Rehash(ctx context.Context, u *entity.User, password string) error
+	Create(ctx context.Context, u *entity.User) (string, error)
+	// UpdatePassword is keep current password in recent passwords up to histories and update it in a transaction
+	UpdatePassword(ctx context.Context, u *entity.User, pass string, histories int) error
+	UpdateAuthed(ctx context.Context, u *entity.User) error
+	IncrementFailedLogins(ctx context.Context, u *entity.User) error
+	Lock(ctx context.Context, u *entity.User, until time.Time) error
+	Unlock(ctx context.Context, u *entity.User) error
+	Update(ctx context.Context, u *entity.User) error
+	Delete(ctx context.Context, u *entity.User) error
 }
@@ -0,0 +1,8 @@
+package service
+
+import "github.com/gotoeveryone/auth-api/app/domain/entity"
+
+// Mailer is service for delivering mail to user.
+type Mailer interface {
+	Send(m entity.Mail) error
+}
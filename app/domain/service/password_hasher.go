@@ -0,0 +1,13 @@
+package service
+
+// PasswordHasher is service for hashing password and verifying password with the hash.
+type PasswordHasher interface {
+	// Hash is return hash of the password with configured algorithm and parameters
+	Hash(password string) (string, error)
+	// Verify is return error if the password does not match the hash
+	Verify(hash, password string) error
+	// NeedsRehash is whether the hash is made with outdated algorithm or parameters
+	NeedsRehash(hash string) bool
+	// MaxLength is maximum bytes of password used for the hash, longer password is not able to be hashed (0 is unlimited)
+	MaxLength() int
+}
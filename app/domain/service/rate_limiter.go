@@ -0,0 +1,11 @@
+package service
+
+import "time"
+
+// RateLimiter is service for limiting requests with token bucket.
+// Implementation sharing buckets between instances (e.g. Redis) is able to be plugged.
+type RateLimiter interface {
+	// Take is take a token from the bucket of the key, which is refilled with burst tokens in period.
+	// Return wait duration until a token is available if the bucket is empty.
+	Take(key string, burst int, period time.Duration) (bool, time.Duration, error)
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+// Length of head of API key stored for identifying the key in listing
+const apiKeyDisplayLength = 11
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository is create personal API key management repository
+func NewAPIKeyRepository(db *gorm.DB) repository.APIKey {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+// Create is create API key data and return issued key
+func (r apiKeyRepository) Create(ctx context.Context, k *entity.APIKey) (string, error) {
+	token, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	key := config.APIKeyPrefix + token
+	k.Prefix = key[:apiKeyDisplayLength]
+	k.KeyHash = hashToken(key)
+	return key, r.db.WithContext(ctx).Create(k).Error
+}
+
+// Find is find API key data
+func (r apiKeyRepository) Find(ctx context.Context, id uint) (*entity.APIKey, error) {
+	return r.find(ctx, &entity.APIKey{ID: id})
+}
+
+// FindAll is find all API key data of the user
+func (r apiKeyRepository) FindAll(ctx context.Context, userID uint) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	if err := r.db.WithContext(ctx).Where(&entity.APIKey{UserID: userID}).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindByKey is find API key data from the key
+func (r apiKeyRepository) FindByKey(ctx context.Context, key string) (*entity.APIKey, error) {
+	return r.find(ctx, &entity.APIKey{KeyHash: hashToken(key)})
+}
+
+// Touch is record the time when API key is used
+func (r apiKeyRepository) Touch(ctx context.Context, k *entity.APIKey) error {
+	now := time.Now()
+	k.LastUsedAt = &now
+	return r.db.WithContext(ctx).Model(k).Update("last_used_at", now).Error
+}
+
+// Delete is delete API key data
+func (r apiKeyRepository) Delete(ctx context.Context, k *entity.APIKey) error {
+	return r.db.WithContext(ctx).Delete(k).Error
+}
+
+func (r apiKeyRepository) find(ctx context.Context, where *entity.APIKey) (*entity.APIKey, error) {
+	var k entity.APIKey
+	err := r.db.WithContext(ctx).Where(where).First(&k).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
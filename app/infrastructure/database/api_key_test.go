@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `api_keys`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := apiKeyRepository{db: db}
+
+	k := entity.APIKey{UserID: 1, Name: "script"}
+	key, err := r.Create(ctx, &k)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, config.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, k.Prefix))
+	assert.Len(t, k.Prefix, apiKeyDisplayLength)
+	assert.Equal(t, hashToken(key), k.KeyHash)
+}
+
+func TestFindAPIKey(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := apiKeyRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys`")).
+			WithArgs(hashToken("test"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		k, err := r.FindByKey(ctx, "test")
+		assert.Nil(t, err)
+		assert.Nil(t, k)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+
+		k, err := r.Find(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2), k.UserID)
+	}
+}
+
+func TestFindAllAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE `api_keys`.`user_id` = ? ORDER BY id")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	r := apiKeyRepository{db: db}
+	keys, err := r.FindAll(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `last_used_at`=? WHERE `id` = ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := apiKeyRepository{db: db}
+	k := entity.APIKey{ID: 1}
+	assert.Nil(t, r.Touch(ctx, &k))
+	assert.NotNil(t, k.LastUsedAt)
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `api_keys`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := apiKeyRepository{db: db}
+	assert.Nil(t, r.Delete(ctx, &entity.APIKey{ID: 1}))
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository is create audit event management repository
+func NewAuditLogRepository(db *gorm.DB) repository.AuditLog {
+	return &auditLogRepository{
+		db: db,
+	}
+}
+
+// Create is append audit event
+func (r auditLogRepository) Create(ctx context.Context, e *entity.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// FindAll is find audit events matched to filter, newer events are first
+func (r auditLogRepository) FindAll(ctx context.Context, f entity.AuditFilter) ([]entity.AuditEvent, error) {
+	q := r.db.WithContext(ctx).Model(&entity.AuditEvent{})
+	if f.Type != nil {
+		q = q.Where("type = ?", *f.Type)
+	}
+	if f.Account != nil {
+		q = q.Where("account = ?", *f.Account)
+	}
+	if f.ActorID != nil {
+		q = q.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.TargetID != nil {
+		q = q.Where("target_id = ?", *f.TargetID)
+	}
+	if f.Since != nil {
+		q = q.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		q = q.Where("created_at < ?", *f.Until)
+	}
+	if f.Cursor > 0 {
+		q = q.Where("id < ?", f.Cursor)
+	}
+
+	var events []entity.AuditEvent
+	if err := q.Order("id DESC").Limit(f.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
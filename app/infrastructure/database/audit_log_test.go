@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAuditEvent(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `audit_events`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := auditLogRepository{db: db}
+
+	e := entity.AuditEvent{Type: entity.AuditLoginFailed, Account: "testuser", IPAddress: "192.0.2.1"}
+	assert.Nil(t, r.Create(ctx, &e))
+	assert.Equal(t, uint(1), e.ID)
+}
+
+func TestFindAllAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := auditLogRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_events` ORDER BY id DESC LIMIT ?")).
+			WithArgs(21).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+
+		events, err := r.FindAll(ctx, entity.AuditFilter{Limit: 21})
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+	}
+	{
+		since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_events` WHERE type = ? AND target_id = ? AND created_at >= ? AND id < ? ORDER BY id DESC LIMIT ?")).
+			WithArgs(entity.AuditLoginFailed, 2, since, 10, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+		typ := entity.AuditLoginFailed
+		target := uint(2)
+		events, err := r.FindAll(ctx, entity.AuditFilter{Type: &typ, TargetID: &target, Since: &since, Cursor: 10, Limit: 5})
+		assert.Nil(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, uint(9), events[0].ID)
+	}
+}
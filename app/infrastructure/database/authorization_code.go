@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type authorizationCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthorizationCodeRepository is create authorization code management repository
+func NewAuthorizationCodeRepository(db *gorm.DB) repository.AuthorizationCode {
+	return &authorizationCodeRepository{
+		db: db,
+	}
+}
+
+// Create is create authorization code data and return issued code
+func (r authorizationCodeRepository) Create(ctx context.Context, ac *entity.AuthorizationCode) (string, error) {
+	code, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	ac.CodeHash = hashToken(code)
+	return code, r.db.WithContext(ctx).Create(ac).Error
+}
+
+// FindByCode is find authorization code data from issued code
+func (r authorizationCodeRepository) FindByCode(ctx context.Context, code string) (*entity.AuthorizationCode, error) {
+	var ac entity.AuthorizationCode
+	err := r.db.WithContext(ctx).Where(&entity.AuthorizationCode{CodeHash: hashToken(code)}).First(&ac).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ac, nil
+}
+
+// Use is mark authorization code as used, return false if it is already used
+func (r authorizationCodeRepository) Use(ctx context.Context, ac *entity.AuthorizationCode) (bool, error) {
+	if ac.UsedAt != nil {
+		return false, nil
+	}
+	res := r.db.WithContext(ctx).Model(ac).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
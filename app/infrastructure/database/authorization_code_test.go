@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAuthorizationCode(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `authorization_codes`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := authorizationCodeRepository{db: db}
+
+	ac := entity.AuthorizationCode{ClientID: "client", UserID: 1}
+	code, err := r.Create(ctx, &ac)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, code)
+	assert.Equal(t, hashToken(code), ac.CodeHash)
+}
+
+func TestFindAuthorizationCode(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := authorizationCodeRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `authorization_codes`")).
+			WithArgs(hashToken("test"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		ac, err := r.FindByCode(ctx, "test")
+		assert.Nil(t, err)
+		assert.Nil(t, ac)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `authorization_codes`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "client_id"}).AddRow(1, "client"))
+
+		ac, err := r.FindByCode(ctx, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, "client", ac.ClientID)
+	}
+}
+
+func TestUseAuthorizationCode(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := authorizationCodeRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `authorization_codes` SET `used_at`=? WHERE used_at IS NULL AND `id` = ?")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, &entity.AuthorizationCode{ID: 1})
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// used by other request
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `authorization_codes`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, &entity.AuthorizationCode{ID: 1})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+	{
+		now := time.Now()
+		used, err := r.Use(ctx, &entity.AuthorizationCode{ID: 1, UsedAt: &now})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
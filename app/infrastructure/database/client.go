@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+var errSecretNotMatched = errors.New("client secret is not matched")
+
+type clientRepository struct {
+	db *gorm.DB
+}
+
+// NewClientRepository is create OAuth client management repository
+func NewClientRepository(db *gorm.DB) repository.Client {
+	return &clientRepository{
+		db: db,
+	}
+}
+
+// Create is create client data with issued client ID and return issued secret
+func (r clientRepository) Create(ctx context.Context, c *entity.Client) (string, error) {
+	id, err := config.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	secret, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	c.ClientID = id
+	c.SecretHash = hashToken(secret)
+	return secret, r.db.WithContext(ctx).Create(c).Error
+}
+
+// Find is find client data
+func (r clientRepository) Find(ctx context.Context, id uint) (*entity.Client, error) {
+	return r.find(ctx, "id = ?", id)
+}
+
+// FindByClientID is find client data by client ID
+func (r clientRepository) FindByClientID(ctx context.Context, clientID string) (*entity.Client, error) {
+	return r.find(ctx, "client_id = ?", clientID)
+}
+
+// FindAll is find all client data
+func (r clientRepository) FindAll(ctx context.Context) ([]entity.Client, error) {
+	var clients []entity.Client
+	if err := r.db.WithContext(ctx).Order("id").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// MatchSecret is check secret matching from client has secret.
+// Secret is random and long enough, so that it is hashed without salt like other tokens.
+func (r clientRepository) MatchSecret(ctx context.Context, hashedSecret, secret string) error {
+	if subtle.ConstantTimeCompare([]byte(hashedSecret), []byte(hashToken(secret))) != 1 {
+		return errSecretNotMatched
+	}
+	return nil
+}
+
+// RegenerateSecret is replace secret of client and return issued secret
+func (r clientRepository) RegenerateSecret(ctx context.Context, c *entity.Client) (string, error) {
+	secret, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	c.SecretHash = hashToken(secret)
+	return secret, r.db.WithContext(ctx).Model(c).UpdateColumn("secret_hash", c.SecretHash).Error
+}
+
+// Update is update client data
+func (r clientRepository) Update(ctx context.Context, c *entity.Client) error {
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+// Delete is delete client data
+func (r clientRepository) Delete(ctx context.Context, c *entity.Client) error {
+	return r.db.WithContext(ctx).Delete(c).Error
+}
+
+// Condition is not built from struct, zero value of it is ignored and matches any client
+func (r clientRepository) find(ctx context.Context, query string, arg any) (*entity.Client, error) {
+	var c entity.Client
+	err := r.db.WithContext(ctx).Where(query, arg).First(&c).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
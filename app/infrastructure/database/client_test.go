@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateClient(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `clients`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := clientRepository{db: db}
+
+	c := entity.Client{Name: "test"}
+	secret, err := r.Create(ctx, &c)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, c.ClientID)
+	assert.Nil(t, r.MatchSecret(ctx, c.SecretHash, secret))
+	assert.NotNil(t, r.MatchSecret(ctx, c.SecretHash, "invalid"))
+}
+
+func TestFindClient(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := clientRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `clients` WHERE id = ?")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		c, err := r.Find(ctx, 1)
+		assert.Nil(t, err)
+		assert.Nil(t, c)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `clients` WHERE client_id = ?")).
+			WithArgs("test", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "client_id"}).AddRow(1, "test"))
+
+		c, err := r.FindByClientID(ctx, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, "test", c.ClientID)
+	}
+}
+
+func TestFindAllClients(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `clients` ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	r := clientRepository{db: db}
+	clients, err := r.FindAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, clients, 2)
+}
+
+func TestRegenerateClientSecret(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `clients` SET `secret_hash`=? WHERE `id` = ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := clientRepository{db: db}
+
+	c := entity.Client{ID: 1, SecretHash: "old"}
+	secret, err := r.RegenerateSecret(ctx, &c)
+	assert.Nil(t, err)
+	assert.Nil(t, r.MatchSecret(ctx, c.SecretHash, secret))
+}
+
+func TestDeleteClient(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `clients`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := clientRepository{db: db}
+	assert.Nil(t, r.Delete(ctx, &entity.Client{ID: 1}))
+}
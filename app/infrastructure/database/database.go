@@ -11,14 +11,8 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-var (
-	// Instance of connected database
-	dbManager *gorm.DB
-)
-
-// Init is execute database connection initial setting
-func Init(debug bool, dbConfig config.DB) error {
-	fmt.Sprintln(dbConfig.Host, dbConfig.Port)
+// Open is connect to database and migrate tables, the connection is passed to repositories
+func Open(debug bool, dbConfig config.DB) (*gorm.DB, error) {
 	c := mysqlDriver.Config{
 		User:                 dbConfig.User,
 		Passwd:               dbConfig.Password,
@@ -35,21 +29,38 @@ func Init(debug bool, dbConfig config.DB) error {
 		logMode = logger.Info
 	}
 
-	var err error
-	dbManager, err = gorm.Open(mysql.New(mysql.Config{
+	db, err := gorm.Open(mysql.New(mysql.Config{
 		DSN: c.FormatDSN(),
 	}), &gorm.Config{
 		Logger: logger.Default.LogMode(logMode),
 	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if err := registerQueryTimeout(db, dbConfig.QueryTimeout); err != nil {
+		return nil, err
+	}
+
+	// Mail address of user existing before verification was introduced is treated as verified,
+	// otherwise the user is not able to login when verification is required
+	backfill := db.Migrator().HasTable(&entity.User{}) && !db.Migrator().HasColumn(&entity.User{}, "MailVerifiedAt")
 
 	// マイグレーション実行
-	if err := dbManager.AutoMigrate(entity.User{}); err != nil {
-		return err
+	if err := db.AutoMigrate(entity.User{}, entity.RevokedToken{}, entity.RefreshToken{}, entity.Invitation{}, entity.PasswordReset{}, entity.PasswordHistory{}, entity.MailVerification{}, entity.RecoveryCode{}, entity.Client{}, entity.AuthorizationCode{}, entity.APIKey{}, entity.Session{}, entity.AuditEvent{}); err != nil {
+		return nil, err
 	}
+	if backfill {
+		if err := backfillMailVerified(db); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
 
-	return nil
+// Mark mail address of all existing users as verified at the registration
+func backfillMailVerified(db *gorm.DB) error {
+	return db.Model(&entity.User{}).Where("mail_verified_at IS NULL").
+		UpdateColumn("mail_verified_at", gorm.Expr("created_at")).Error
 }
@@ -1,25 +1,24 @@
 package database
 
 import (
+	"regexp"
+	"testing"
+
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-var (
-	mock sqlmock.Sqlmock
-)
-
-func init() {
-	mock = initDBMock()
-}
-
-func initDBMock() sqlmock.Sqlmock {
+// Create connection to mock database, each test has own connection and expectations
+func newTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	// defer db.Close()
+	t.Cleanup(func() {
+		db.Close()
+	})
 
 	gdb, err := gorm.Open(mysql.New(mysql.Config{
 		Conn:                      db,
@@ -28,9 +27,16 @@ func initDBMock() sqlmock.Sqlmock {
 		SkipDefaultTransaction: true,
 	})
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
+	return gdb, mock
+}
+
+func TestBackfillMailVerified(t *testing.T) {
+	db, mock := newTestDB(t)
 
-	dbManager = gdb
-	return mock
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `mail_verified_at`=created_at WHERE mail_verified_at IS NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	assert.Nil(t, backfillMailVerified(db))
+	assert.Nil(t, mock.ExpectationsWereMet())
 }
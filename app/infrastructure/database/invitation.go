@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository is create invitation code management repository
+func NewInvitationRepository(db *gorm.DB) repository.Invitation {
+	return &invitationRepository{
+		db: db,
+	}
+}
+
+// Create is create invitation data and return issued code
+func (r invitationRepository) Create(ctx context.Context, i *entity.Invitation) (string, error) {
+	code, err := config.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	i.CodeHash = hashToken(code)
+	return code, r.db.WithContext(ctx).Create(i).Error
+}
+
+// Find is find invitation data
+func (r invitationRepository) Find(ctx context.Context, id uint) (*entity.Invitation, error) {
+	var i entity.Invitation
+	err := r.db.WithContext(ctx).Where(&entity.Invitation{ID: id}).First(&i).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+// FindAll is find all invitation data
+func (r invitationRepository) FindAll(ctx context.Context) ([]entity.Invitation, error) {
+	var invitations []entity.Invitation
+	if err := r.db.WithContext(ctx).Order("id").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Use is mark invitation code as used, return false if it is unknown, expired or already used
+func (r invitationRepository) Use(ctx context.Context, code string) (bool, error) {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&entity.Invitation{}).
+		Where(&entity.Invitation{CodeHash: hashToken(code)}).
+		Where("used_at IS NULL AND expired_at > ?", now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
+
+// Delete is delete invitation data
+func (r invitationRepository) Delete(ctx context.Context, i *entity.Invitation) error {
+	return r.db.WithContext(ctx).Delete(i).Error
+}
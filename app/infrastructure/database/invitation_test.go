@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateInvitation(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `invitations`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := invitationRepository{db: db}
+
+	i := entity.Invitation{CreatedBy: 1}
+	code, err := r.Create(ctx, &i)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, code)
+	assert.Equal(t, hashToken(code), i.CodeHash)
+}
+
+func TestFindInvitation(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := invitationRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `invitations`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		i, err := r.Find(ctx, 1)
+		assert.Nil(t, err)
+		assert.Nil(t, i)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `invitations`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		i, err := r.Find(ctx, 1)
+		assert.Nil(t, err)
+		assert.NotNil(t, i)
+	}
+}
+
+func TestFindAllInvitations(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `invitations` ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	r := invitationRepository{db: db}
+	invitations, err := r.FindAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, invitations, 2)
+}
+
+func TestUseInvitation(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := invitationRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `invitations` SET `used_at`=? WHERE `invitations`.`code_hash` = ? AND (used_at IS NULL AND expired_at > ?)")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, "test")
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// unknown, expired or already used
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `invitations`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, "test")
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
+
+func TestDeleteInvitation(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `invitations`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := invitationRepository{db: db}
+	assert.Nil(t, r.Delete(ctx, &entity.Invitation{ID: 1}))
+}
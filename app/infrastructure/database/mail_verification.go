@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type mailVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewMailVerificationRepository is create mail verification token management repository
+func NewMailVerificationRepository(db *gorm.DB) repository.MailVerification {
+	return &mailVerificationRepository{
+		db: db,
+	}
+}
+
+// Create is create mail verification data and return issued token
+func (r mailVerificationRepository) Create(ctx context.Context, v *entity.MailVerification) (string, error) {
+	token, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	v.TokenHash = hashToken(token)
+	return token, r.db.WithContext(ctx).Create(v).Error
+}
+
+// FindByToken is find mail verification data from issued token
+func (r mailVerificationRepository) FindByToken(ctx context.Context, token string) (*entity.MailVerification, error) {
+	var v entity.MailVerification
+	err := r.db.WithContext(ctx).Where(&entity.MailVerification{TokenHash: hashToken(token)}).First(&v).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Use is mark mail verification token as used, return false if it is already used
+func (r mailVerificationRepository) Use(ctx context.Context, v *entity.MailVerification) (bool, error) {
+	if v.UsedAt != nil {
+		return false, nil
+	}
+	res := r.db.WithContext(ctx).Model(v).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
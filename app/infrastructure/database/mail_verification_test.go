@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMailVerification(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `mail_verifications`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := mailVerificationRepository{db: db}
+
+	v := entity.MailVerification{UserID: 1, MailAddress: "test@example.com"}
+	token, err := r.Create(ctx, &v)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashToken(token), v.TokenHash)
+}
+
+func TestFindMailVerificationByToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := mailVerificationRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `mail_verifications`")).
+			WithArgs(hashToken("test"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		v, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.Nil(t, v)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `mail_verifications`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		v, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.NotNil(t, v)
+	}
+}
+
+func TestUseMailVerification(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := mailVerificationRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `mail_verifications`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, &entity.MailVerification{ID: 1})
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// used by other request
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `mail_verifications`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, &entity.MailVerification{ID: 1})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+	{
+		now := time.Now()
+		used, err := r.Use(ctx, &entity.MailVerification{ID: 1, UsedAt: &now})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
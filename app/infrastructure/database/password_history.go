@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository is create previous password management repository
+func NewPasswordHistoryRepository(db *gorm.DB) repository.PasswordHistory {
+	return &passwordHistoryRepository{
+		db: db,
+	}
+}
+
+// FindRecent is find recent passwords of the user, newer passwords are first
+func (r passwordHistoryRepository) FindRecent(ctx context.Context, userID uint, limit int) ([]entity.PasswordHistory, error) {
+	var histories []entity.PasswordHistory
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&histories).Error; err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRecentPasswordHistories(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_histories` WHERE user_id = ? ORDER BY id DESC LIMIT ?")).
+		WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "password"}).
+			AddRow(2, 1, "hashed2").
+			AddRow(1, 1, "hashed1"))
+
+	r := passwordHistoryRepository{db: db}
+
+	histories, err := r.FindRecent(ctx, 1, 3)
+	assert.Nil(t, err)
+	assert.Len(t, histories, 2)
+	assert.Equal(t, "hashed2", histories[0].Password)
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository is create password reset token management repository
+func NewPasswordResetRepository(db *gorm.DB) repository.PasswordReset {
+	return &passwordResetRepository{
+		db: db,
+	}
+}
+
+// Create is create password reset data and return issued token
+func (r passwordResetRepository) Create(ctx context.Context, pr *entity.PasswordReset) (string, error) {
+	token, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	pr.TokenHash = hashToken(token)
+	return token, r.db.WithContext(ctx).Create(pr).Error
+}
+
+// FindByToken is find password reset data from issued token
+func (r passwordResetRepository) FindByToken(ctx context.Context, token string) (*entity.PasswordReset, error) {
+	var pr entity.PasswordReset
+	err := r.db.WithContext(ctx).Where(&entity.PasswordReset{TokenHash: hashToken(token)}).First(&pr).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// Use is mark password reset token as used, return false if it is already used
+func (r passwordResetRepository) Use(ctx context.Context, pr *entity.PasswordReset) (bool, error) {
+	if pr.UsedAt != nil {
+		return false, nil
+	}
+	res := r.db.WithContext(ctx).Model(pr).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
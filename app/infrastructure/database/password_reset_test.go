@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePasswordReset(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `password_resets`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := passwordResetRepository{db: db}
+
+	pr := entity.PasswordReset{UserID: 1}
+	token, err := r.Create(ctx, &pr)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashToken(token), pr.TokenHash)
+}
+
+func TestFindPasswordResetByToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := passwordResetRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_resets`")).
+			WithArgs(hashToken("test"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		pr, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.Nil(t, pr)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_resets`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		pr, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.NotNil(t, pr)
+	}
+}
+
+func TestUsePasswordReset(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := passwordResetRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `password_resets`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, &entity.PasswordReset{ID: 1})
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// used by other request
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `password_resets`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, &entity.PasswordReset{ID: 1})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+	{
+		now := time.Now()
+		used, err := r.Use(ctx, &entity.PasswordReset{ID: 1, UsedAt: &now})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository is create recovery code management repository
+func NewRecoveryCodeRepository(db *gorm.DB) repository.RecoveryCode {
+	return &recoveryCodeRepository{
+		db: db,
+	}
+}
+
+// Create is replace recovery codes of the user with new codes and return issued codes
+func (r recoveryCodeRepository) Create(ctx context.Context, userID uint, n int) ([]string, error) {
+	codes := make([]string, n)
+	rows := make([]entity.RecoveryCode, n)
+	for i := range codes {
+		code, err := config.RandomToken(8)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		rows[i] = entity.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}
+	}
+
+	if err := r.Delete(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := r.db.WithContext(ctx).Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Use is mark recovery code as used, return false if it is unknown or already used
+func (r recoveryCodeRepository) Use(ctx context.Context, userID uint, code string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.RecoveryCode{}).
+		Where(&entity.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}).
+		Where("used_at IS NULL").
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
+
+// Delete is delete all recovery codes of the user
+func (r recoveryCodeRepository) Delete(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where(&entity.RecoveryCode{UserID: userID}).Delete(&entity.RecoveryCode{}).Error
+}
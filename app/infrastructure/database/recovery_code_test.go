@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `recovery_codes` WHERE `recovery_codes`.`user_id` = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `recovery_codes`")).
+		WillReturnResult(sqlmock.NewResult(1, 3))
+
+	r := recoveryCodeRepository{db: db}
+	codes, err := r.Create(ctx, 1, 3)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 3)
+	assert.NotEqual(t, codes[0], codes[1])
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := recoveryCodeRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `recovery_codes` SET `used_at`=? WHERE (`recovery_codes`.`user_id` = ? AND `recovery_codes`.`code_hash` = ?) AND used_at IS NULL")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, 1, "test")
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// unknown or already used
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `recovery_codes`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, 1, "test")
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
+
+func TestDeleteRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `recovery_codes`")).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+
+	r := recoveryCodeRepository{db: db}
+	assert.Nil(t, r.Delete(ctx, 1))
+}
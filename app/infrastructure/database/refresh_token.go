@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository is create refresh token management repository
+func NewRefreshTokenRepository(db *gorm.DB) repository.RefreshToken {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create is create refresh token data and return issued token
+func (r refreshTokenRepository) Create(ctx context.Context, t *entity.RefreshToken) (string, error) {
+	token, err := config.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	t.TokenHash = hashToken(token)
+	return token, r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindByToken is find refresh token data from issued token
+func (r refreshTokenRepository) FindByToken(ctx context.Context, token string) (*entity.RefreshToken, error) {
+	var t entity.RefreshToken
+	err := r.db.WithContext(ctx).Where(&entity.RefreshToken{TokenHash: hashToken(token)}).First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Use is mark refresh token as used, return false if it is already used
+func (r refreshTokenRepository) Use(ctx context.Context, t *entity.RefreshToken) (bool, error) {
+	if t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(t).Where("used_at IS NULL").Update("used_at", now)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return (res.RowsAffected > 0), nil
+}
+
+// RevokeFamily is revoke all refresh tokens issued from the same authentication
+func (r refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where(&entity.RefreshToken{FamilyID: familyID}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser is revoke all refresh tokens of the user
+func (r refreshTokenRepository) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where(&entity.RefreshToken{UserID: userID}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+// Get hashed token for storing, the token has enough entropy so that salt is unnecessary
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := refreshTokenRepository{db: db}
+
+	rt := entity.RefreshToken{UserID: 1, FamilyID: "family"}
+	token, err := r.Create(ctx, &rt)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, hashToken(token), rt.TokenHash)
+	assert.NotEqual(t, token, rt.TokenHash)
+}
+
+func TestFindRefreshTokenByToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := refreshTokenRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `refresh_tokens`")).
+			WithArgs(hashToken("test"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		rt, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.Nil(t, rt)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `refresh_tokens`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		rt, err := r.FindByToken(ctx, "test")
+		assert.Nil(t, err)
+		assert.NotNil(t, rt)
+	}
+}
+
+func TestUseRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := refreshTokenRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		used, err := r.Use(ctx, &entity.RefreshToken{ID: 1})
+		assert.Nil(t, err)
+		assert.True(t, used)
+	}
+	{
+		// used by other request
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens`")).
+			WillReturnResult(sqlmock.NewResult(1, 0))
+
+		used, err := r.Use(ctx, &entity.RefreshToken{ID: 1})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+	{
+		now := time.Now()
+		used, err := r.Use(ctx, &entity.RefreshToken{ID: 1, UsedAt: &now})
+		assert.Nil(t, err)
+		assert.False(t, used)
+	}
+}
+
+func TestRevokeFamily(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	r := refreshTokenRepository{db: db}
+	assert.Nil(t, r.RevokeFamily(ctx, "family"))
+}
+
+func TestRevokeUser(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at`=? WHERE `refresh_tokens`.`user_id` = ? AND revoked_at IS NULL")).
+		WillReturnResult(sqlmock.NewResult(1, 3))
+
+	r := refreshTokenRepository{db: db}
+	assert.Nil(t, r.RevokeUser(ctx, 1))
+}
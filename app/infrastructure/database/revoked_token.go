@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRevokedTokenRepository is create revoked token management repository
+func NewRevokedTokenRepository(db *gorm.DB) repository.RevokedToken {
+	return &revokedTokenRepository{
+		db: db,
+	}
+}
+
+// Revoke is register token as revoked until expiration
+func (r revokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiredAt time.Time) error {
+	// Records of expired token are no longer needed
+	if err := r.db.WithContext(ctx).Where("expired_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(&entity.RevokedToken{
+		TokenID:   tokenID,
+		ExpiredAt: expiredAt,
+	}).Error
+}
+
+// IsRevoked is confirm to token already revoked
+func (r revokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.RevokedToken{}).Where(&entity.RevokedToken{TokenID: tokenID}).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return (count > 0), nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `revoked_tokens`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := revokedTokenRepository{db: db}
+	assert.Nil(t, r.Revoke(ctx, "test", time.Now().Add(time.Hour)))
+}
+
+func TestIsRevoked(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := revokedTokenRepository{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens`")).
+		WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	e, err := r.IsRevoked(ctx, "test")
+	assert.Nil(t, err)
+	assert.True(t, e)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens`")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	e, err = r.IsRevoked(ctx, "test")
+	assert.Nil(t, err)
+	assert.False(t, e)
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+// Interval of recording the time when session is seen, for reducing writes on every request
+const sessionTouchInterval = time.Minute
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository is create signed in session management repository
+func NewSessionRepository(db *gorm.DB) repository.Session {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+// Create is create session data
+func (r sessionRepository) Create(ctx context.Context, s *entity.Session) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// Find is find session data
+func (r sessionRepository) Find(ctx context.Context, id uint) (*entity.Session, error) {
+	return r.find(ctx, &entity.Session{ID: id})
+}
+
+// FindByFamily is find session data from family of refresh tokens
+func (r sessionRepository) FindByFamily(ctx context.Context, familyID string) (*entity.Session, error) {
+	return r.find(ctx, &entity.Session{FamilyID: familyID})
+}
+
+// FindAll is find active session data of the user
+func (r sessionRepository) FindAll(ctx context.Context, userID uint) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.db.WithContext(ctx).Where(&entity.Session{UserID: userID}).
+		Where("revoked_at IS NULL AND expired_at > ?", time.Now()).
+		Order("id").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Update is update session data
+func (r sessionRepository) Update(ctx context.Context, s *entity.Session) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+// Touch is record the time when session is seen, it is skipped if recently recorded
+func (r sessionRepository) Touch(ctx context.Context, id uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&entity.Session{ID: id}).
+		Where("last_seen_at IS NULL OR last_seen_at < ?", now.Add(-sessionTouchInterval)).
+		Update("last_seen_at", now).Error
+}
+
+// Revoke is revoke session
+func (r sessionRepository) Revoke(ctx context.Context, s *entity.Session) error {
+	now := time.Now()
+	s.RevokedAt = &now
+	return r.db.WithContext(ctx).Model(s).Where("revoked_at IS NULL").Update("revoked_at", now).Error
+}
+
+// RevokeUser is revoke all sessions of the user
+func (r sessionRepository) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where(&entity.Session{UserID: userID}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r sessionRepository) find(ctx context.Context, where *entity.Session) (*entity.Session, error) {
+	var s entity.Session
+	err := r.db.WithContext(ctx).Where(where).First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSession(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := sessionRepository{db: db}
+
+	s := entity.Session{UserID: 1, FamilyID: "family", ExpiredAt: time.Now().Add(time.Hour)}
+	assert.Nil(t, r.Create(ctx, &s))
+	assert.Equal(t, uint(1), s.ID)
+}
+
+func TestFindSession(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := sessionRepository{db: db}
+
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+			WithArgs("family", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		s, err := r.FindByFamily(ctx, "family")
+		assert.Nil(t, err)
+		assert.Nil(t, s)
+	}
+	{
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+
+		s, err := r.Find(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2), s.UserID)
+	}
+}
+
+func TestFindAllSessions(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions` WHERE `sessions`.`user_id` = ? AND (revoked_at IS NULL AND expired_at > ?) ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	r := sessionRepository{db: db}
+	sessions, err := r.FindAll(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 2)
+}
+
+func TestTouchSession(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `last_seen_at`=? WHERE (last_seen_at IS NULL OR last_seen_at < ?) AND `id` = ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := sessionRepository{db: db}
+	assert.Nil(t, r.Touch(ctx, 1))
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := sessionRepository{db: db}
+
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at`=? WHERE revoked_at IS NULL AND `id` = ?")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		s := entity.Session{ID: 1}
+		assert.Nil(t, r.Revoke(ctx, &s))
+		assert.NotNil(t, s.RevokedAt)
+	}
+	{
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at`=? WHERE `sessions`.`user_id` = ? AND revoked_at IS NULL")).
+			WillReturnResult(sqlmock.NewResult(1, 2))
+
+		assert.Nil(t, r.RevokeUser(ctx, 1))
+	}
+}
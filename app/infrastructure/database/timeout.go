@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Key of statement setting holding the query context before the timeout is set
+const queryTimeoutKey = "auth-api:query_timeout"
+
+// Context of the statement before the timeout is set and function to release the query context
+type queryTimeout struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// Callback registered at the position in processor
+type callbackRegisterer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// Register callbacks canceling each query that takes longer than the duration,
+// earlier deadline of the context passed by repository is kept.
+// Transaction is included in the duration for creating, updating and deleting.
+// Row is not limited because rows are read after the callbacks.
+// Context of the statement is restored after the query, so that chain running more queries is not canceled.
+func registerQueryTimeout(db *gorm.DB, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		ctx, cancel := context.WithTimeout(parent, d)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryTimeoutKey, queryTimeout{parent: parent, cancel: cancel})
+	}
+	after := func(tx *gorm.DB) {
+		if v, ok := tx.InstanceGet(queryTimeoutKey); ok {
+			qt := v.(queryTimeout)
+			qt.cancel()
+			tx.Statement.Context = qt.parent
+		}
+	}
+
+	cb := db.Callback()
+	for name, r := range map[string][2]callbackRegisterer{
+		"create": {cb.Create().Before("*"), cb.Create().After("*")},
+		"query":  {cb.Query().Before("*"), cb.Query().After("*")},
+		"update": {cb.Update().Before("*"), cb.Update().After("*")},
+		"delete": {cb.Delete().Before("*"), cb.Delete().After("*")},
+		"raw":    {cb.Raw().Before("*"), cb.Raw().After("*")},
+	} {
+		if err := r[0].Register("timeout:before_"+name, before); err != nil {
+			return err
+		}
+		if err := r[1].Register("timeout:after_"+name, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	if err := registerQueryTimeout(db, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	r := NewSessionRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s, err := r.Find(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), s.ID)
+
+	// Each query has own deadline, so slow query is canceled
+	start := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	_, err = r.Find(ctx, 1)
+	assert.NotNil(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions`")).
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = r.Touch(ctx, 1)
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Deadline of the context is kept if it is earlier
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+		WillDelayFor(30 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	_, err = r.Find(short, 1)
+	assert.NotNil(t, err)
+}
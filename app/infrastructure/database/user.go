@@ -1,27 +1,34 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
 	"gorm.io/gorm"
 )
 
-type userRepository struct{}
+type userRepository struct {
+	db     *gorm.DB
+	hasher service.PasswordHasher
+}
 
-// NewUserRepository is create user management repository
-func NewUserRepository() repository.User {
-	return &userRepository{}
+// NewUserRepository is create user management repository, password is hashed with the hasher
+func NewUserRepository(db *gorm.DB, h service.PasswordHasher) repository.User {
+	return &userRepository{
+		db:     db,
+		hasher: h,
+	}
 }
 
 // Exists is confirm to account already exists
-func (r userRepository) Exists(account string) (bool, error) {
+func (r userRepository) Exists(ctx context.Context, account string) (bool, error) {
 	var count int64
-	err := dbManager.Model(&entity.User{}).Where(&entity.User{Account: account}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&entity.User{}).Where(&entity.User{Account: account}).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -29,9 +36,9 @@ func (r userRepository) Exists(account string) (bool, error) {
 }
 
 // Find is execute user data finding
-func (r userRepository) Find(id uint) (*entity.User, error) {
+func (r userRepository) Find(ctx context.Context, id uint) (*entity.User, error) {
 	var u entity.User
-	err := dbManager.Where(&entity.User{ID: id}).First(&u).Error
+	err := r.db.WithContext(ctx).Where(&entity.User{ID: id}).First(&u).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -41,10 +48,35 @@ func (r userRepository) Find(id uint) (*entity.User, error) {
 	return &u, nil
 }
 
+// FindAll is find users matched to filter and return total count
+func (r userRepository) FindAll(ctx context.Context, f entity.UserFilter) ([]entity.User, int64, error) {
+	q := r.db.WithContext(ctx).Model(&entity.User{})
+	if f.Role != nil {
+		q = q.Where("role = ?", *f.Role)
+	}
+	if f.IsEnable != nil {
+		q = q.Where("is_enable = ?", *f.IsEnable)
+	}
+	if f.IsActive != nil {
+		q = q.Where("is_active = ?", *f.IsActive)
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []entity.User
+	if err := q.Session(&gorm.Session{}).Order("id").Offset(f.Offset).Limit(f.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
 // FindByAccount is find user data from account and password
-func (r userRepository) FindByAccount(account string) (*entity.User, error) {
+func (r userRepository) FindByAccount(ctx context.Context, account string) (*entity.User, error) {
 	var u entity.User
-	err := dbManager.Where(&entity.User{Account: account, IsEnable: true}).First(&u).Error
+	err := r.db.WithContext(ctx).Where(&entity.User{Account: account, IsEnable: true}).First(&u).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -54,13 +86,40 @@ func (r userRepository) FindByAccount(account string) (*entity.User, error) {
 	return &u, nil
 }
 
+// FindByMailAddress is find enabled users having the mail address
+func (r userRepository) FindByMailAddress(ctx context.Context, mailAddress string) ([]entity.User, error) {
+	var users []entity.User
+	err := r.db.WithContext(ctx).Where(&entity.User{MailAddress: mailAddress, IsEnable: true}).Order("id").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // MatchPassword is check password matching from user has password
-func (r userRepository) MatchPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (r userRepository) MatchPassword(ctx context.Context, hashedPassword, password string) error {
+	// Hashing is expensive, so it is skipped if the request is already canceled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.hasher.Verify(hashedPassword, password)
+}
+
+// Rehash is update hash of the matched password if it is made with outdated algorithm or parameters
+func (r userRepository) Rehash(ctx context.Context, u *entity.User, password string) error {
+	if !r.hasher.NeedsRehash(u.Password) {
+		return nil
+	}
+	hashed, err := r.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	u.Password = hashed
+	return r.db.WithContext(ctx).Model(u).UpdateColumn("password", hashed).Error
 }
 
 // Create is create user data and return generate password
-func (r userRepository) Create(u *entity.User) (string, error) {
+func (r userRepository) Create(ctx context.Context, u *entity.User) (string, error) {
 	// Issue initial password
 	password := config.RandomString(16)
 	hashPassword, err := r.hashedPassword(password)
@@ -68,6 +127,8 @@ func (r userRepository) Create(u *entity.User) (string, error) {
 		return "", err
 	}
 	u.Password = hashPassword
+	now := time.Now()
+	u.PasswordChangedAt = &now
 
 	// If not specify role, use default role
 	if u.Role == "" {
@@ -75,32 +136,96 @@ func (r userRepository) Create(u *entity.User) (string, error) {
 	}
 
 	u.IsEnable = true
-	return password, dbManager.Create(u).Error
+	return password, r.db.WithContext(ctx).Create(u).Error
 }
 
-// UpdatePassword is update new password
-func (r userRepository) UpdatePassword(u *entity.User, pass string) error {
+// UpdatePassword is update new password, current password is kept in recent passwords up to histories.
+// Histories older than them are deleted, so that all histories are deleted if histories is 0.
+func (r userRepository) UpdatePassword(ctx context.Context, u *entity.User, pass string, histories int) error {
 	newpass, err := r.hashedPassword(pass)
 	if err != nil {
 		return err
 	}
+	current := u.Password
+	now := time.Now()
 	u.Password = newpass
+	u.PasswordChangedAt = &now
 	u.IsActive = true
-	return dbManager.Save(u).Error
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(u).Error; err != nil {
+			return err
+		}
+		if histories > 0 && current != "" {
+			if err := tx.Create(&entity.PasswordHistory{UserID: u.ID, Password: current}).Error; err != nil {
+				return err
+			}
+		}
+		return pruneHistories(tx, u.ID, histories)
+	})
+}
+
+// Delete histories of the user except recent ones
+func pruneHistories(tx *gorm.DB, userID uint, keep int) error {
+	q := tx.Where("user_id = ?", userID)
+	if keep > 0 {
+		var ids []uint
+		err := tx.Model(&entity.PasswordHistory{}).Where("user_id = ?", userID).
+			Order("id DESC").Offset(keep-1).Limit(1).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		q = q.Where("id < ?", ids[0])
+	}
+	return q.Delete(&entity.PasswordHistory{}).Error
 }
 
 // UpdateAuthed is update authenticated date
-func (r userRepository) UpdateAuthed(u *entity.User) error {
+func (r userRepository) UpdateAuthed(ctx context.Context, u *entity.User) error {
 	now := time.Now()
 	u.LastLogged = &now
-	return dbManager.Save(u).Error
+	return r.db.WithContext(ctx).Save(u).Error
 }
 
-// Get hashed password
-func (r userRepository) hashedPassword(pass string) (string, error) {
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+// IncrementFailedLogins is count up failed logins and reload the count
+func (r userRepository) IncrementFailedLogins(ctx context.Context, u *entity.User) error {
+	err := r.db.WithContext(ctx).Model(u).UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(hashedPass), nil
+	return r.db.WithContext(ctx).Model(u).Select("failed_logins").Where(&entity.User{ID: u.ID}).Take(u).Error
+}
+
+// Lock is lock account until the time
+func (r userRepository) Lock(ctx context.Context, u *entity.User, until time.Time) error {
+	u.LockedUntil = &until
+	return r.db.WithContext(ctx).Model(u).UpdateColumn("locked_until", until).Error
+}
+
+// Unlock is unlock account and reset failed logins
+func (r userRepository) Unlock(ctx context.Context, u *entity.User) error {
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	return r.db.WithContext(ctx).Model(u).UpdateColumns(map[string]any{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+}
+
+// Update is update user data
+func (r userRepository) Update(ctx context.Context, u *entity.User) error {
+	return r.db.WithContext(ctx).Save(u).Error
+}
+
+// Delete is delete user data
+func (r userRepository) Delete(ctx context.Context, u *entity.User) error {
+	return r.db.WithContext(ctx).Delete(u).Error
+}
+
+// Get hashed password
+func (r userRepository) hashedPassword(pass string) (string, error) {
+	return r.hasher.Hash(pass)
 }
@@ -1,44 +1,70 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/infrastructure/hasher"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var testPasswordHash = config.PasswordHash{
+	Algorithm:         config.PasswordHashBcrypt,
+	BcryptCost:        bcrypt.MinCost,
+	Argon2Memory:      64,
+	Argon2Iterations:  1,
+	Argon2Parallelism: 1,
+}
+
+func newTestUserRepository(t *testing.T, db *gorm.DB) userRepository {
+	h, err := hasher.NewHasher(testPasswordHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userRepository{db: db, hasher: h}
+}
+
 func TestExists(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users`")).
 		WithArgs("test").
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
-	r := userRepository{}
+	r := userRepository{db: db}
 
 	v := "test"
-	e, err := r.Exists(v)
+	e, err := r.Exists(ctx, v)
 	assert.Nil(t, err)
 	assert.True(t, e)
 
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users`")).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
-	e, err = r.Exists(v)
+	e, err = r.Exists(ctx, v)
 	assert.Nil(t, err)
 	assert.False(t, e)
 }
 
 func TestFindUser(t *testing.T) {
-	r := userRepository{}
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := userRepository{db: db}
 	id := uint(1)
 
 	{
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-		e, err := r.Find(id)
+		e, err := r.Find(ctx, id)
 		assert.Nil(t, err)
 		assert.Nil(t, e)
 	}
@@ -46,21 +72,76 @@ func TestFindUser(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-		e, err := r.Find(id)
+		e, err := r.Find(ctx, id)
 		assert.Nil(t, err)
 		assert.NotNil(t, e)
 	}
+	{
+		// query is not executed after the request is canceled
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		e, err := r.Find(canceled, id)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, e)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFindAllUsers(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := userRepository{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users` WHERE role = ? AND is_enable = ?")).
+		WithArgs(entity.RoleGeneral, true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE role = ? AND is_enable = ? ORDER BY id LIMIT ? OFFSET ?")).
+		WithArgs(entity.RoleGeneral, true, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+	role := entity.RoleGeneral
+	enabled := true
+	users, total, err := r.FindAll(ctx, entity.UserFilter{Role: &role, IsEnable: &enabled, Offset: 1, Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, users, 1)
+	assert.Equal(t, uint(2), users[0].ID)
+}
+
+func TestFindAllUsersWithQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	if err := registerQueryTimeout(db, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	r := userRepository{db: db}
+
+	// Query after counting is not canceled by timeout of counting
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users`")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` ORDER BY id LIMIT ?")).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	users, total, err := r.FindAll(ctx, entity.UserFilter{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, users, 1)
+	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
 func TestFindByAccount(t *testing.T) {
-	r := userRepository{}
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := userRepository{db: db}
 	v := "test"
 
 	{
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
 			WillReturnRows(sqlmock.NewRows([]string{"account"}))
 
-		u, err := r.FindByAccount(v)
+		u, err := r.FindByAccount(ctx, v)
 		assert.Nil(t, err)
 		assert.Nil(t, u)
 	}
@@ -68,35 +149,94 @@ func TestFindByAccount(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
 			WillReturnRows(sqlmock.NewRows([]string{"account"}).AddRow("test"))
 
-		u, err := r.FindByAccount(v)
+		u, err := r.FindByAccount(ctx, v)
 		assert.Nil(t, err)
 		assert.NotNil(t, u)
 	}
 }
 
+func TestFindByMailAddress(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := userRepository{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE `users`.`mail_address` = ? AND `users`.`is_enable` = ? ORDER BY id")).
+		WithArgs("test@example.com", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	users, err := r.FindByMailAddress(ctx, "test@example.com")
+	assert.Nil(t, err)
+	assert.Len(t, users, 2)
+}
+
 func TestMatchPassword(t *testing.T) {
-	r := userRepository{}
+	ctx := context.Background()
+	db, _ := newTestDB(t)
+	r := newTestUserRepository(t, db)
 
 	s := "testtest"
 	d, err := r.hashedPassword(s)
 	assert.Nil(t, err)
-	assert.NotNil(t, r.MatchPassword(d, "testtest1"))
-	assert.Nil(t, r.MatchPassword(d, s))
+	assert.NotNil(t, r.MatchPassword(ctx, d, "testtest1"))
+	assert.Nil(t, r.MatchPassword(ctx, d, s))
+
+	// request is already canceled
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, r.MatchPassword(canceled, d, s), context.Canceled)
+}
+
+func TestRehashPassword(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := newTestUserRepository(t, db)
+
+	{
+		// hash is up to date
+		hashed, err := r.hashedPassword("password")
+		assert.Nil(t, err)
+
+		u := entity.User{ID: 1, Password: hashed}
+		assert.Nil(t, r.Rehash(ctx, &u, "password"))
+		assert.Equal(t, hashed, u.Password)
+	}
+	{
+		// hash of other algorithm
+		c := testPasswordHash
+		c.Algorithm = config.PasswordHashArgon2id
+		h, err := hasher.NewHasher(c)
+		assert.Nil(t, err)
+		hashed, err := h.Hash("password")
+		assert.Nil(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `password`=? WHERE `id` = ?")).
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		u := entity.User{ID: 1, Password: hashed}
+		assert.Nil(t, r.Rehash(ctx, &u, "password"))
+		assert.NotEqual(t, hashed, u.Password)
+		assert.Nil(t, r.MatchPassword(ctx, u.Password, "password"))
+		assert.False(t, r.hasher.NeedsRehash(u.Password))
+	}
 }
 
 func TestCreateUser(t *testing.T) {
-	r := userRepository{}
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	r := newTestUserRepository(t, db)
 
 	{
 		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		u := entity.User{}
-		pass, err := r.Create(&u)
+		pass, err := r.Create(ctx, &u)
 		assert.Nil(t, err)
 		assert.NotEmpty(t, pass)
 		assert.Equal(t, u.Role, entity.RoleGeneral)
 		assert.True(t, u.IsEnable)
+		assert.NotNil(t, u.PasswordChangedAt)
 	}
 
 	{
@@ -106,7 +246,7 @@ func TestCreateUser(t *testing.T) {
 		u := entity.User{
 			Role: entity.RoleAdministrator,
 		}
-		pass, err := r.Create(&u)
+		pass, err := r.Create(ctx, &u)
 		assert.Nil(t, err)
 		assert.NotEmpty(t, pass)
 		assert.Equal(t, u.Role, entity.RoleAdministrator)
@@ -114,31 +254,151 @@ func TestCreateUser(t *testing.T) {
 }
 
 func TestUpdatePassword(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users`")).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `password_histories`")).
+		WithArgs(1, "current", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `password_histories` WHERE user_id = ? ORDER BY id DESC LIMIT ? OFFSET ?")).
+		WithArgs(1, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `password_histories` WHERE user_id = ? AND id < ?")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	r := userRepository{}
+	r := newTestUserRepository(t, db)
 
 	np := "newpassword"
 	u := entity.User{
-		ID: 1,
+		ID:       1,
+		Password: "current",
 	}
-	assert.Nil(t, r.UpdatePassword(&u, np))
-	assert.Nil(t, r.MatchPassword(u.Password, np))
+	assert.Nil(t, r.UpdatePassword(ctx, &u, np, 2))
+	assert.Nil(t, r.MatchPassword(ctx, u.Password, np))
 	assert.True(t, u.IsActive)
+	assert.NotNil(t, u.PasswordChangedAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePasswordWithoutHistory(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `password_histories` WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	r := newTestUserRepository(t, db)
+
+	u := entity.User{
+		ID:       1,
+		Password: "current",
+	}
+	assert.Nil(t, r.UpdatePassword(ctx, &u, "newpassword", 0))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePasswordRollback(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users`")).
+		WillReturnError(errors.New("failed"))
+	mock.ExpectRollback()
+
+	r := newTestUserRepository(t, db)
+
+	u := entity.User{
+		ID:       1,
+		Password: "current",
+	}
+	assert.NotNil(t, r.UpdatePassword(ctx, &u, "newpassword", 2))
+	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateAuthed(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users`")).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	r := userRepository{}
+	r := userRepository{db: db}
 
 	s := time.Now()
 	u := entity.User{
 		ID:         1,
 		LastLogged: &s,
 	}
-	assert.Nil(t, r.UpdateAuthed(&u))
+	assert.Nil(t, r.UpdateAuthed(ctx, &u))
 	assert.False(t, s.Equal(*u.LastLogged))
 }
+
+func TestUpdateUser(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := userRepository{db: db}
+	assert.Nil(t, r.Update(ctx, &entity.User{ID: 1, Name: "test"}))
+}
+
+func TestIncrementFailedLogins(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `failed_logins`=failed_logins + 1 WHERE `id` = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `failed_logins` FROM `users`")).
+		WillReturnRows(sqlmock.NewRows([]string{"failed_logins"}).AddRow(3))
+
+	r := userRepository{db: db}
+	u := entity.User{ID: 1}
+	assert.Nil(t, r.IncrementFailedLogins(ctx, &u))
+	assert.Equal(t, uint(3), u.FailedLogins)
+}
+
+func TestLockUser(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `locked_until`=? WHERE `id` = ?")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := userRepository{db: db}
+	u := entity.User{ID: 1}
+	until := time.Now().Add(time.Minute)
+	assert.Nil(t, r.Lock(ctx, &u, until))
+	assert.True(t, u.Locked(time.Now()))
+}
+
+func TestUnlockUser(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `failed_logins`=?,`locked_until`=? WHERE `id` = ?")).
+		WithArgs(0, nil, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := userRepository{db: db}
+	until := time.Now().Add(time.Minute)
+	u := entity.User{ID: 1, FailedLogins: 5, LockedUntil: &until}
+	assert.Nil(t, r.Unlock(ctx, &u))
+	assert.Equal(t, uint(0), u.FailedLogins)
+	assert.False(t, u.Locked(time.Now()))
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	db, mock := newTestDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `users`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	r := userRepository{db: db}
+	assert.Nil(t, r.Delete(ctx, &entity.User{ID: 1}))
+}
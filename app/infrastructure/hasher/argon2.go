@@ -0,0 +1,132 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix   = "$argon2id$"
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	errInvalidArgon2Hash = errors.New("argon2id hash is invalid")
+)
+
+type argon2idHasher struct {
+	// Memory in KiB
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// argon2Params is parameters decoded from PHC string
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Create Argon2id hasher, hash is PHC string format (e.g. "$argon2id$v=19$m=65536,t=3,p=4$salt$key")
+func newArgon2id(memory, iterations uint32, parallelism uint8) (algorithm, error) {
+	if iterations < 1 || parallelism < 1 {
+		return nil, fmt.Errorf("argon2id iterations and parallelism must be at least 1")
+	}
+	// Memory must be at least 8 KiB per thread (RFC 9106 section 3.1)
+	if memory < 8*uint32(parallelism) {
+		return nil, fmt.Errorf("argon2id memory must be at least %d KiB: %d", 8*uint32(parallelism), memory)
+	}
+	return &argon2idHasher{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}, nil
+}
+
+// Hash is return Argon2id hash of the password with random salt
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify is compare the password with Argon2id hash, parameters of the hash are used
+func (h *argon2idHasher) Verify(hash, password string) error {
+	p, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+// NeedsRehash is whether parameters of the hash differ from configured parameters
+func (h *argon2idHasher) NeedsRehash(hash string) bool {
+	p, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return p.memory != h.memory || p.iterations != h.iterations || p.parallelism != h.parallelism ||
+		len(p.salt) != argon2SaltLength || len(p.key) != argon2KeyLength
+}
+
+// MaxLength is unlimited, Argon2id uses whole password
+func (h *argon2idHasher) MaxLength() int {
+	return 0
+}
+
+// Identify is whether the hash is Argon2id hash
+func (h *argon2idHasher) Identify(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+// Decode parameters, salt and key from PHC string of Argon2id
+func decodeArgon2id(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errInvalidArgon2Hash
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if p.iterations < 1 || p.parallelism < 1 {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return nil, errInvalidArgon2Hash
+	}
+	return p, nil
+}
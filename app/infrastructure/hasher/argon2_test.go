@@ -0,0 +1,65 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgon2id(t *testing.T) {
+	h, err := newArgon2id(64, 1, 1)
+	assert.Nil(t, err)
+
+	hashed, err := h.Hash("password")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"))
+	assert.True(t, h.Identify(hashed))
+	assert.Nil(t, h.Verify(hashed, "password"))
+	assert.Equal(t, errPasswordMismatch, h.Verify(hashed, "invalid"))
+	assert.False(t, h.NeedsRehash(hashed))
+
+	// salt is random
+	other, err := h.Hash("password")
+	assert.Nil(t, err)
+	assert.NotEqual(t, hashed, other)
+
+	// parameters are changed, hash is verified with its own parameters
+	changed, err := newArgon2id(128, 2, 1)
+	assert.Nil(t, err)
+	assert.True(t, changed.NeedsRehash(hashed))
+	assert.Nil(t, changed.Verify(hashed, "password"))
+}
+
+func TestArgon2idReferenceHash(t *testing.T) {
+	// Hash generated by reference implementation: echo -n password | argon2 somesalt -id -t 2 -m 16 -p 4 -l 32
+	hashed := "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"
+
+	h, err := newArgon2id(64, 1, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, h.Verify(hashed, "password"))
+	assert.True(t, h.NeedsRehash(hashed))
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	for _, hashed := range []string{
+		"",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+	} {
+		_, err := decodeArgon2id(hashed)
+		assert.Equal(t, errInvalidArgon2Hash, err, hashed)
+	}
+
+	p, err := decodeArgon2id("$argon2id$v=19$m=64,t=1,p=2$c2FsdA$a2V5")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(64), p.memory)
+	assert.Equal(t, uint32(1), p.iterations)
+	assert.Equal(t, uint8(2), p.parallelism)
+	assert.Equal(t, []byte("salt"), p.salt)
+	assert.Equal(t, []byte("key"), p.key)
+}
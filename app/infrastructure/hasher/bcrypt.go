@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Maximum bytes of password hashed with bcrypt
+const bcryptMaxLength = 72
+
+type bcryptHasher struct {
+	cost int
+}
+
+// Create bcrypt hasher, hash is modular crypt format (e.g. "$2a$10$...")
+func newBcrypt(cost int) (algorithm, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d: %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	return &bcryptHasher{cost: cost}, nil
+}
+
+// Hash is return bcrypt hash of the password
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify is compare the password with bcrypt hash
+func (h *bcryptHasher) Verify(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return errPasswordMismatch
+	}
+	return err
+}
+
+// NeedsRehash is whether cost of the hash differs from configured cost
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+// MaxLength is 72 bytes, bcrypt uses only first 72 bytes of password and rejects longer password
+func (h *bcryptHasher) MaxLength() int {
+	return bcryptMaxLength
+}
+
+// Identify is whether the hash is bcrypt hash
+func (h *bcryptHasher) Identify(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
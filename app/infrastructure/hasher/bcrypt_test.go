@@ -0,0 +1,30 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcrypt(t *testing.T) {
+	h, err := newBcrypt(bcrypt.MinCost)
+	assert.Nil(t, err)
+
+	hashed, err := h.Hash("password")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$2a$04$"))
+	assert.True(t, h.Identify(hashed))
+	assert.Nil(t, h.Verify(hashed, "password"))
+	assert.Equal(t, errPasswordMismatch, h.Verify(hashed, "invalid"))
+	assert.False(t, h.NeedsRehash(hashed))
+
+	// cost is changed
+	other, err := newBcrypt(bcrypt.MinCost + 1)
+	assert.Nil(t, err)
+	assert.True(t, other.NeedsRehash(hashed))
+	assert.Nil(t, other.Verify(hashed, "password"))
+
+	assert.False(t, h.Identify("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5"))
+}
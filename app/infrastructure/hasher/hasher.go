@@ -0,0 +1,75 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+)
+
+var (
+	errPasswordMismatch = errors.New("password does not match")
+	errUnknownHash      = errors.New("hash algorithm is unknown")
+)
+
+// algorithm is password hasher of an algorithm, identifying hashes made by itself
+type algorithm interface {
+	service.PasswordHasher
+	Identify(hash string) bool
+}
+
+// hasher is hash password with preferred algorithm, and verify hashes of all supported algorithms
+type hasher struct {
+	preferred  algorithm
+	algorithms []algorithm
+}
+
+// NewHasher is create password hasher with configured algorithm.
+// Hashes of other supported algorithms are also verified, so that they are able to be rehashed.
+func NewHasher(c config.PasswordHash) (service.PasswordHasher, error) {
+	b, err := newBcrypt(c.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newArgon2id(c.Argon2Memory, c.Argon2Iterations, c.Argon2Parallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hasher{algorithms: []algorithm{b, a}}
+	switch c.Algorithm {
+	case config.PasswordHashBcrypt:
+		h.preferred = b
+	case config.PasswordHashArgon2id:
+		h.preferred = a
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm: %s", c.Algorithm)
+	}
+	return h, nil
+}
+
+// Hash is return hash of the password with preferred algorithm
+func (h *hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Verify is verify the password with algorithm of the hash
+func (h *hasher) Verify(hash, password string) error {
+	for _, a := range h.algorithms {
+		if a.Identify(hash) {
+			return a.Verify(hash, password)
+		}
+	}
+	return errUnknownHash
+}
+
+// NeedsRehash is whether the hash is made with other algorithm or outdated parameters
+func (h *hasher) NeedsRehash(hash string) bool {
+	return !h.preferred.Identify(hash) || h.preferred.NeedsRehash(hash)
+}
+
+// MaxLength is maximum bytes of password for preferred algorithm, new password is hashed with it
+func (h *hasher) MaxLength() int {
+	return h.preferred.MaxLength()
+}
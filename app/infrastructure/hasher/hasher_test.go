@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"testing"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testConfig = config.PasswordHash{
+	Algorithm:         config.PasswordHashArgon2id,
+	BcryptCost:        bcrypt.MinCost,
+	Argon2Memory:      64,
+	Argon2Iterations:  1,
+	Argon2Parallelism: 1,
+}
+
+func TestNewHasher(t *testing.T) {
+	for _, c := range []config.PasswordHash{
+		{Algorithm: "md5", BcryptCost: 10, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1},
+		{Algorithm: config.PasswordHashBcrypt, BcryptCost: 100, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1},
+		{Algorithm: config.PasswordHashArgon2id, BcryptCost: 10, Argon2Memory: 64, Argon2Iterations: 0, Argon2Parallelism: 1},
+		{Algorithm: config.PasswordHashArgon2id, BcryptCost: 10, Argon2Memory: 8, Argon2Iterations: 1, Argon2Parallelism: 2},
+	} {
+		_, err := NewHasher(c)
+		assert.NotNil(t, err, c)
+	}
+}
+
+func TestHasher(t *testing.T) {
+	bc := testConfig
+	bc.Algorithm = config.PasswordHashBcrypt
+	b, err := NewHasher(bc)
+	assert.Nil(t, err)
+	a, err := NewHasher(testConfig)
+	assert.Nil(t, err)
+
+	bcryptHash, err := b.Hash("password")
+	assert.Nil(t, err)
+	argon2Hash, err := a.Hash("password")
+	assert.Nil(t, err)
+
+	// hash of both algorithms is verified
+	for _, h := range []string{bcryptHash, argon2Hash} {
+		assert.Nil(t, a.Verify(h, "password"))
+		assert.Nil(t, b.Verify(h, "password"))
+		assert.Equal(t, errPasswordMismatch, a.Verify(h, "invalid"))
+	}
+	assert.Equal(t, errUnknownHash, a.Verify("plain", "plain"))
+
+	// hash of other algorithm is rehashed
+	assert.True(t, a.NeedsRehash(bcryptHash))
+	assert.False(t, a.NeedsRehash(argon2Hash))
+	assert.True(t, b.NeedsRehash(argon2Hash))
+	assert.False(t, b.NeedsRehash(bcryptHash))
+	assert.True(t, a.NeedsRehash(""))
+
+	// length of password is limited by preferred algorithm
+	assert.Equal(t, 72, b.MaxLength())
+	assert.Equal(t, 0, a.MaxLength())
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/rs/zerolog/log"
+)
+
+type logMailer struct {
+	from string
+	dir  string
+}
+
+// NewLogMailer is create mailer writing mail to file or log instead of delivering, for local development
+func NewLogMailer(c config.Mail) service.Mailer {
+	return &logMailer{
+		from: c.From,
+		dir:  c.Dir,
+	}
+}
+
+// Send is write mail to file in the directory.
+// Only recipient and subject are logged if directory is not specified, body includes secret token.
+func (m logMailer) Send(e entity.Mail) error {
+	if m.dir == "" {
+		log.Info().Str("to", e.To).Str("subject", e.Subject).Msg("mail is not delivered")
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	suffix, err := config.RandomToken(6)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102150405"), suffix)
+	return os.WriteFile(filepath.Join(m.dir, name), message(m.from, e), 0600)
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailerSend(t *testing.T) {
+	e := entity.Mail{To: "test@example.com", Subject: "subject", Body: "secret token"}
+
+	// output to log without body
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() {
+		log.Logger = logger
+	})
+	assert.Nil(t, NewLogMailer(config.Mail{}).Send(e))
+	assert.Contains(t, buf.String(), "test@example.com")
+	assert.NotContains(t, buf.String(), "secret token")
+
+	// output to file
+	dir := filepath.Join(t.TempDir(), "mails")
+	m := NewLogMailer(config.Mail{From: "no-reply@example.com", Dir: dir})
+	assert.Nil(t, m.Send(e))
+	assert.Nil(t, m.Send(e))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+
+	b, err := os.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "To: test@example.com\r\n")
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+)
+
+type smtpMailer struct {
+	config config.Mail
+}
+
+// NewSMTPMailer is create mailer delivering with SMTP server
+func NewSMTPMailer(c config.Mail) service.Mailer {
+	return &smtpMailer{
+		config: c,
+	}
+}
+
+// Send is deliver mail with SMTP server, STARTTLS is used if the server supports it
+func (m smtpMailer) Send(e entity.Mail) error {
+	var auth smtp.Auth
+	if m.config.User != "" {
+		auth = smtp.PlainAuth("", m.config.User, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, m.config.From, []string{e.To}, message(m.config.From, e))
+}
+
+// Build message with headers in RFC 5322 format
+func message(from string, e entity.Mail) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", e.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", e.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(e.Body)
+	return b.Bytes()
+}
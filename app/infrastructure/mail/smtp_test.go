@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	b := string(message("no-reply@example.com", entity.Mail{
+		To:      "test@example.com",
+		Subject: "パスワード再設定",
+		Body:    "body",
+	}))
+
+	assert.Contains(t, b, "From: no-reply@example.com\r\n")
+	assert.Contains(t, b, "To: test@example.com\r\n")
+	assert.Contains(t, b, "Subject: =?UTF-8?q?")
+	assert.True(t, strings.HasSuffix(b, "\r\n\r\nbody"))
+}
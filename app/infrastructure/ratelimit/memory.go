@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+)
+
+const (
+	sweepInterval time.Duration = time.Minute
+)
+
+// bucket is token bucket for a key
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// Time the bucket is refilled, it is not necessary to keep after that
+	full time.Time
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemoryRateLimiter is create rate limiter keeping buckets in memory of the process
+func NewMemoryRateLimiter() service.RateLimiter {
+	return &memoryRateLimiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Take is take a token from the bucket of the key
+func (l *memoryRateLimiter) Take(key string, burst int, period time.Duration) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	// tokens per second
+	rate := float64(burst) / period.Seconds()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.updated).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, seconds((1 - b.tokens) / rate), nil
+	}
+	b.tokens--
+	b.full = now.Add(seconds((float64(burst) - b.tokens) / rate))
+	return true, 0, nil
+}
+
+// Remove refilled buckets periodically, they are the same as new bucket
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	for key, b := range l.buckets {
+		if !b.full.After(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// Convert seconds to duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimiterTake(t *testing.T) {
+	now := time.Now()
+	l := NewMemoryRateLimiter().(*memoryRateLimiter)
+	l.now = func() time.Time { return now }
+
+	// burst
+	for i := 0; i < 3; i++ {
+		ok, _, err := l.Take("key", 3, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, err := l.Take("key", 3, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, wait)
+
+	// other key is not affected
+	ok, _, _ = l.Take("other", 3, time.Minute)
+	assert.True(t, ok)
+
+	// refilled by rate
+	now = now.Add(20 * time.Second)
+	ok, _, _ = l.Take("key", 3, time.Minute)
+	assert.True(t, ok)
+	ok, wait, _ = l.Take("key", 3, time.Minute)
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, wait)
+}
+
+func TestMemoryRateLimiterSweep(t *testing.T) {
+	now := time.Now()
+	l := NewMemoryRateLimiter().(*memoryRateLimiter)
+	l.now = func() time.Time { return now }
+
+	_, _, _ = l.Take("key", 3, time.Minute)
+	_, _, _ = l.Take("other", 3, time.Hour)
+	assert.Len(t, l.buckets, 2)
+
+	// refilled bucket is removed
+	now = now.Add(2 * time.Minute)
+	_, _, _ = l.Take("new", 3, time.Minute)
+	assert.Len(t, l.buckets, 2)
+	assert.NotContains(t, l.buckets, "key")
+}
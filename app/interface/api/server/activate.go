@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// ActivateHandler is enable account with update password.
+// Password is verified same as login, so failures are counted and locked account is not activated.
+// Tokens issued before activation are revoked.
+// @Summary Enable account with update password
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.Activate true "request data"
+// @Success 200
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/activate [post]
+func (mw *jwtMiddleware) ActivateHandler(c *gin.Context) {
+	// Execute validation
+	var a entity.Activate
+	if err := c.ShouldBindJSON(&a); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &a))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	// Deny change to same password
+	if a.Password == a.NewPassword {
+		errorBadRequest(c, errSamePassword)
+		return
+	}
+
+	user, err := mw.verifyPassword(c.Request.Context(), a.Account, a.Password)
+	if err != nil {
+		recordAudit(mw.audit, c, entity.AuditEvent{
+			Type:    entity.AuditLoginFailed,
+			Account: a.Account,
+			Detail:  err.Error(),
+		})
+		errorUnauthorized(c, err)
+		return
+	}
+
+	// Second factor is required same as login, otherwise it is bypassed by activation
+	if user.MFAEnabled {
+		if a.Code == "" {
+			errorUnauthorized(c, errMFARequired)
+			return
+		}
+
+		totp, recoveryCode := secondFactor(a.Code)
+		verified, err := mw.verifySecondFactor(c.Request.Context(), user, totp, recoveryCode)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if !verified {
+			err := mw.failLogin(c.Request.Context(), user)
+			if err != errAccountLocked {
+				err = errInvalidMFACode
+			}
+			recordAudit(mw.audit, c, auditEvent(entity.AuditLoginFailed, user, user, err.Error()))
+			errorUnauthorized(c, err)
+			return
+		}
+		if user.FailedLogins > 0 || user.LockedUntil != nil {
+			if err := mw.repo.Unlock(c.Request.Context(), user); err != nil {
+				errorInternalServerError(c, err)
+				return
+			}
+		}
+	}
+
+	if !validatePassword(c, mw.policy, user, a.NewPassword) {
+		return
+	}
+
+	// Tokens issued with previous version are rejected
+	user.TokenVersion++
+	if err := mw.repo.UpdatePassword(c.Request.Context(), user, a.NewPassword, mw.policy.Histories()); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := mw.refresh.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := mw.sessions.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(mw.audit, c, auditEvent(entity.AuditActivated, user, user, ""))
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newActivateRouter(t *testing.T, d AuthDeps) *gin.Engine {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	middleware, err := NewAuthMiddleware(d).Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/v1/activate", middleware.ActivateHandler)
+	return r
+}
+
+func activate(r *gin.Engine, p entity.Activate) *httptest.ResponseRecorder {
+	j, _ := json.Marshal(p)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/activate", bytes.NewBuffer(j))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestActivateFailedInvalidParam(t *testing.T) {
+	r := newActivateRouter(t, newTestAuthDeps(t))
+
+	w := activate(r, entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "password",
+		},
+	})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestActivateFailedSamePassword(t *testing.T) {
+	r := newActivateRouter(t, newTestAuthDeps(t))
+
+	w := activate(r, entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "password",
+		},
+		NewPassword: "password",
+	})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestActivateFailedAccountNotExist(t *testing.T) {
+	r := newActivateRouter(t, newTestAuthDeps(t))
+
+	w := activate(r, entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "HogeFuga001",
+		},
+		NewPassword: "HogeFuga001New",
+	})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+}
+
+func TestActivateFailedPasswordNotMatched(t *testing.T) {
+	ur := &mock.UserRepository{User: &entity.User{
+		ID:       1,
+		Account:  "testuser",
+		IsEnable: true,
+	}, IsMatchPassword: false}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	d.Lockout = config.Lockout{Threshold: 2, Duration: time.Minute}
+	r := newActivateRouter(t, d)
+
+	p := entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "HogeFuga001",
+		},
+		NewPassword: "HogeFuga001New",
+	}
+	w := activate(r, p)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, ur.User.FailedLogins, uint(1))
+
+	// failures are counted same as login and the account is locked
+	w = activate(r, p)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errAccountLocked.Error())
+
+	// locked account is not activated even if the password is correct
+	ur.IsMatchPassword = true
+	w = activate(r, p)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errAccountLocked.Error())
+	assert.False(t, ur.User.IsActive)
+}
+
+func TestActivateFailedPasswordPolicy(t *testing.T) {
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
+		Account:  "testuser",
+		IsEnable: true,
+	}, IsMatchPassword: true}
+	r := newActivateRouter(t, d)
+
+	w := activate(r, entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "HogeFuga001",
+		},
+		NewPassword: "password1",
+	})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Contains(t, w.Body.String(), "too common")
+}
+
+func TestActivateFailedMFA(t *testing.T) {
+	ur := &mock.UserRepository{User: &entity.User{
+		ID:         1,
+		Account:    "testuser",
+		IsEnable:   true,
+		MFAEnabled: true,
+		MFASecret:  testTOTPSecret,
+	}, IsMatchPassword: true}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	r := newActivateRouter(t, d)
+
+	p := entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "HogeFuga001",
+		},
+		NewPassword: "HogeFuga001New",
+	}
+
+	// second factor is not bypassed by activation
+	w := activate(r, p)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errMFARequired.Error())
+
+	p.Code = "000000"
+	w = activate(r, p)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errInvalidMFACode.Error())
+	assert.False(t, ur.User.IsActive)
+
+	p.Code, _ = totpCode(testTOTPSecret, time.Now().Unix()/totpPeriod)
+	w = activate(r, p)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestActivateSuccess(t *testing.T) {
+	initial, _ := bcrypt.GenerateFromPassword([]byte("HogeFuga001"), bcrypt.MinCost)
+	hr := &mock.PasswordHistoryRepository{}
+	ur := &mock.UserRepository{User: &entity.User{
+		ID:           1,
+		Account:      "testuser",
+		Password:     string(initial),
+		IsEnable:     true,
+		TokenVersion: 1,
+	}, Histories: hr}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	d.Policy = NewPasswordPolicy(config.PasswordPolicy{History: 3}, ur, hr, &mock.PasswordHasher{Max: 72})
+	r := newActivateRouter(t, d)
+
+	w := activate(r, entity.Activate{
+		Authenticate: entity.Authenticate{
+			Account:  "testuser",
+			Password: "HogeFuga001",
+		},
+		NewPassword: "HogeFuga001New",
+	})
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	// tokens issued before activation are rejected
+	assert.Equal(t, ur.User.TokenVersion, uint(2))
+
+	// initial password is kept in history after it is updated
+	histories, _ := hr.FindRecent(context.Background(), 1, 10)
+	if assert.Len(t, histories, 1) {
+		assert.Equal(t, string(initial), histories[0].Password)
+	}
+}
@@ -0,0 +1,423 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+const (
+	defaultLimit = 20
+)
+
+type adminUserHandler struct {
+	repo          repository.User
+	recovery      repository.RecoveryCode
+	verifications repository.MailVerification
+	refresh       repository.RefreshToken
+	sessions      repository.Session
+	audit         repository.AuditLog
+	mailer        service.Mailer
+}
+
+// NewAdminUserHandler is create action handler for user management
+func NewAdminUserHandler(ur repository.User, rc repository.RecoveryCode, vr repository.MailVerification, tr repository.RefreshToken, sr repository.Session, al repository.AuditLog, m service.Mailer) handler.AdminUser {
+	return &adminUserHandler{
+		repo:          ur,
+		recovery:      rc,
+		verifications: vr,
+		refresh:       tr,
+		sessions:      sr,
+		audit:         al,
+		mailer:        m,
+	}
+}
+
+// List is get paginated users
+// @Summary Return paginated users
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query int false "page number"
+// @Param limit query int false "number of users per page"
+// @Param role query string false "role" Enums(Administrator, General)
+// @Param enabled query bool false "whether account is enabled"
+// @Param active query bool false "whether account is activated"
+// @Success 200 {object} entity.Users
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users [get]
+func (h *adminUserHandler) List(c *gin.Context) {
+	var p entity.SearchUser
+	if err := c.ShouldBindQuery(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	if p.Page == 0 {
+		p.Page = 1
+	}
+	if p.Limit == 0 {
+		p.Limit = defaultLimit
+	}
+
+	f := entity.UserFilter{
+		IsEnable: p.Enabled,
+		IsActive: p.Active,
+		Offset:   (p.Page - 1) * p.Limit,
+		Limit:    p.Limit,
+	}
+	if p.Role != nil {
+		role := entity.Role(*p.Role)
+		f.Role = &role
+	}
+
+	users, total, err := h.repo.FindAll(c.Request.Context(), f)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	items := []entity.ManagedUser{}
+	for i := range users {
+		items = append(items, users[i].Managed())
+	}
+
+	c.JSON(http.StatusOK, entity.Users{
+		Items: items,
+		Total: total,
+		Page:  p.Page,
+		Limit: p.Limit,
+	})
+}
+
+// Get is get user
+// @Summary Return user
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id} [get]
+func (h *adminUserHandler) Get(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// Update is partial update of user.
+// Changed mail address becomes unverified, and token for verifying it is sent to the address.
+// @Summary Update user partially
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param id path int true "user ID"
+// @Param data body entity.UpdateUser true "request data"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id} [patch]
+func (h *adminUserHandler) Update(c *gin.Context) {
+	var p entity.UpdateUser
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if p.Name != nil {
+		user.Name = *p.Name
+	}
+	if p.Gender != nil {
+		user.Gender = entity.Gender(*p.Gender)
+	}
+	mailChanged := p.MailAddress != nil && *p.MailAddress != user.MailAddress
+	if mailChanged {
+		user.ChangeMailAddress(*p.MailAddress)
+	}
+	if p.Birthday != nil {
+		t, err := time.Parse("2006-01-02", *p.Birthday)
+		if err != nil {
+			errorBadRequest(c, errValidationFailed)
+			return
+		}
+		user.Birthday = entity.Date{Time: t}
+	}
+	previousRole := user.Role
+	if p.Role != nil {
+		user.Role = entity.Role(*p.Role)
+	}
+
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	if mailChanged {
+		if err := sendVerificationMail(c.Request.Context(), h.verifications, h.mailer, user); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+	if user.Role != previousRole {
+		recordAudit(h.audit, c, auditEvent(entity.AuditRoleChange, actor(c), user, string(previousRole)+" -> "+string(user.Role)))
+	}
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// Enable is enable account of user
+// @Summary Enable account of user
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id}/enable [post]
+func (h *adminUserHandler) Enable(c *gin.Context) {
+	h.setEnable(c, true)
+}
+
+// Disable is disable account of user
+// @Summary Disable account of user
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id}/disable [post]
+func (h *adminUserHandler) Disable(c *gin.Context) {
+	h.setEnable(c, false)
+}
+
+// Unlock is unlock account locked by failed logins
+// @Summary Unlock account of user locked by failed logins
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id}/unlock [post]
+func (h *adminUserHandler) Unlock(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Unlock(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditUnlocked, actor(c), user, ""))
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// ExpirePassword is force user to change password on next login
+// @Summary Force user to change password on next login
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id}/password/expire [post]
+func (h *adminUserHandler) ExpirePassword(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	// Inactive user is required to change password before login,
+	// and tokens issued with previous version are rejected until then
+	user.IsActive = false
+	user.TokenVersion++
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.refresh.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.sessions.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditPasswordExpire, actor(c), user, ""))
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// ResetMFA is disable two-factor authentication of user who lost authenticator app and recovery codes
+// @Summary Reset two-factor authentication of user
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 200 {object} entity.ManagedUser
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id}/mfa [delete]
+func (h *adminUserHandler) ResetMFA(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	user.ResetMFA()
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.recovery.Delete(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditMFAReset, actor(c), user, ""))
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// Delete is delete user
+// @Summary Delete user
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "user ID"
+// @Success 204
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/users/{id} [delete]
+func (h *adminUserHandler) Delete(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if isIdentity(c, user) {
+		errorBadRequest(c, errOperateOwnAccount)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditDeleted, actor(c), user, ""))
+
+	c.Status(http.StatusNoContent)
+}
+
+// Change enabled state of user
+func (h *adminUserHandler) setEnable(c *gin.Context, enable bool) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if !enable && isIdentity(c, user) {
+		errorBadRequest(c, errOperateOwnAccount)
+		return
+	}
+
+	user.IsEnable = enable
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	t := entity.AuditDisabled
+	if enable {
+		t = entity.AuditEnabled
+	}
+	recordAudit(h.audit, c, auditEvent(t, actor(c), user, ""))
+
+	c.JSON(http.StatusOK, user.Managed())
+}
+
+// Find user from path parameter, return false if response is already written
+func (h *adminUserHandler) findUser(c *gin.Context) (*entity.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorNotFound(c, errUserNotFound)
+		return nil, false
+	}
+
+	user, err := h.repo.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return nil, false
+	}
+	if user == nil {
+		errorNotFound(c, errUserNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// Check user is the authenticated user
+func isIdentity(c *gin.Context, user *entity.User) bool {
+	identity, _ := c.Get(config.IdentityKey)
+	if v, ok := identity.(*entity.User); ok {
+		return v.ID == user.ID
+	}
+	return false
+}
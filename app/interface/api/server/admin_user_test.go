@@ -0,0 +1,334 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListUsers(t *testing.T) {
+	h := NewAdminUserHandler(&mock.UserRepository{User: &entity.User{
+		ID:       2,
+		Account:  "testuser",
+		IsEnable: true,
+	}}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{})
+
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.GET("/v1/admin/users", h.List)
+
+		req, _ := http.NewRequest("GET", "/v1/admin/users?role=General&enabled=true", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		e := entity.Users{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, int64(1), e.Total)
+		assert.Equal(t, 1, e.Page)
+		assert.Equal(t, defaultLimit, e.Limit)
+		assert.Equal(t, "testuser", e.Items[0].Account)
+		assert.True(t, e.Items[0].IsEnable)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.GET("/v1/admin/users", h.List)
+
+		req, _ := http.NewRequest("GET", "/v1/admin/users?role=Unknown", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		h := NewAdminUserHandler(&mock.UserRepository{}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{})
+		r.GET("/v1/admin/users/:id", h.Get)
+
+		req, _ := http.NewRequest("GET", "/v1/admin/users/2", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusNotFound)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		h := NewAdminUserHandler(&mock.UserRepository{User: &entity.User{ID: 2, Account: "testuser"}}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{})
+		r.GET("/v1/admin/users/:id", h.Get)
+
+		req, _ := http.NewRequest("GET", "/v1/admin/users/2", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		e := entity.ManagedUser{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, "testuser", e.Account)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	verified := time.Now()
+	user := &entity.User{
+		ID:             2,
+		Account:        "testuser",
+		Name:           "Test User",
+		MailAddress:    "test@example.com",
+		MailVerifiedAt: &verified,
+		Role:           entity.RoleGeneral,
+	}
+	ms := &mock.Mailer{}
+	al := &mock.AuditLogRepository{}
+	h := NewAdminUserHandler(&mock.UserRepository{User: user}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, al, ms)
+
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.PATCH("/v1/admin/users/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/users/2", bytes.NewBufferString(`{"mailAddress":"invalid"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.PATCH("/v1/admin/users/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/users/2", bytes.NewBufferString(`{"role":"Administrator","birthday":"2000-01-31"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		// role change is audited
+		assert.Len(t, al.Events, 1)
+		assert.Equal(t, entity.AuditRoleChange, al.Events[0].Type)
+		assert.Equal(t, uint(1), *al.Events[0].ActorID)
+		assert.Equal(t, uint(2), *al.Events[0].TargetID)
+		assert.Equal(t, "General -> Administrator", al.Events[0].Detail)
+
+		e := entity.ManagedUser{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, "Test User", e.Name)
+		assert.Equal(t, entity.RoleAdministrator, e.Role)
+		assert.Equal(t, "2000-01-31", e.Birthday.Format("2006-01-02"))
+	}
+	{
+		// mail address is not changed
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.PATCH("/v1/admin/users/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/users/2", bytes.NewBufferString(`{"mailAddress":"test@example.com"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.True(t, user.MailVerified())
+		assert.Empty(t, ms.Mails)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.PATCH("/v1/admin/users/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/users/2", bytes.NewBufferString(`{"mailAddress":"changed@example.com"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, "changed@example.com", user.MailAddress)
+		assert.False(t, user.MailVerified())
+		assert.Len(t, ms.Mails, 1)
+		assert.Equal(t, "changed@example.com", ms.Mails[0].To)
+	}
+}
+
+func TestDisableUser(t *testing.T) {
+	user := &entity.User{ID: 2, Account: "testuser", IsEnable: true}
+	al := &mock.AuditLogRepository{}
+	h := NewAdminUserHandler(&mock.UserRepository{User: user}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, al, &mock.Mailer{})
+
+	{
+		// own account
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 2}))
+		r.POST("/v1/admin/users/:id/disable", h.Disable)
+
+		req, _ := http.NewRequest("POST", "/v1/admin/users/2/disable", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		assert.True(t, user.IsEnable)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/admin/users/:id/disable", h.Disable)
+		r.POST("/v1/admin/users/:id/enable", h.Enable)
+
+		req, _ := http.NewRequest("POST", "/v1/admin/users/2/disable", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.False(t, user.IsEnable)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/v1/admin/users/2/enable", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.True(t, user.IsEnable)
+
+		assert.Len(t, al.Events, 2)
+		assert.Equal(t, entity.AuditDisabled, al.Events[0].Type)
+		assert.Equal(t, entity.AuditEnabled, al.Events[1].Type)
+		assert.Equal(t, uint(1), *al.Events[1].ActorID)
+		assert.Equal(t, uint(2), *al.Events[1].TargetID)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	user := &entity.User{ID: 2, FailedLogins: 5, LockedUntil: &until}
+	al := &mock.AuditLogRepository{}
+	h := NewAdminUserHandler(&mock.UserRepository{User: user}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, al, &mock.Mailer{})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.POST("/v1/admin/users/:id/unlock", h.Unlock)
+
+	req, _ := http.NewRequest("POST", "/v1/admin/users/2/unlock", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, uint(0), user.FailedLogins)
+	assert.Nil(t, user.LockedUntil)
+	assert.Len(t, al.Events, 1)
+	assert.Equal(t, entity.AuditUnlocked, al.Events[0].Type)
+}
+
+func TestExpirePassword(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 2, IsActive: true, TokenVersion: 1}
+	tr := &mock.RefreshTokenRepository{}
+	sr := &mock.SessionRepository{}
+	al := &mock.AuditLogRepository{}
+	token, _ := tr.Create(ctx, &entity.RefreshToken{UserID: user.ID, FamilyID: "family", ExpiredAt: time.Now().Add(time.Hour)})
+	session := &entity.Session{UserID: user.ID, FamilyID: "family", ExpiredAt: time.Now().Add(time.Hour)}
+	_ = sr.Create(ctx, session)
+	h := NewAdminUserHandler(&mock.UserRepository{User: user}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, tr, sr, al, &mock.Mailer{})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.Use(setIdentity(&entity.User{ID: 1}))
+	r.POST("/v1/admin/users/:id/password/expire", h.ExpirePassword)
+
+	req, _ := http.NewRequest("POST", "/v1/admin/users/2/password/expire", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.False(t, user.IsActive)
+
+	// tokens issued before are rejected
+	assert.Equal(t, uint(2), user.TokenVersion)
+	rt, _ := tr.FindByToken(ctx, token)
+	assert.NotNil(t, rt.RevokedAt)
+	s, _ := sr.Find(ctx, session.ID)
+	assert.NotNil(t, s.RevokedAt)
+
+	assert.Len(t, al.Events, 1)
+	assert.Equal(t, entity.AuditPasswordExpire, al.Events[0].Type)
+	assert.Equal(t, uint(1), *al.Events[0].ActorID)
+	assert.Equal(t, uint(2), *al.Events[0].TargetID)
+}
+
+func TestDeleteUser(t *testing.T) {
+	al := &mock.AuditLogRepository{}
+	h := NewAdminUserHandler(&mock.UserRepository{User: &entity.User{ID: 2, Account: "testuser"}}, &mock.RecoveryCodeRepository{}, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, al, &mock.Mailer{})
+
+	{
+		// own account
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 2}))
+		r.DELETE("/v1/admin/users/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/admin/users/2", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.DELETE("/v1/admin/users/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/admin/users/2", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusNoContent)
+
+		// account of deleted user is kept in the event
+		assert.Len(t, al.Events, 1)
+		assert.Equal(t, entity.AuditDeleted, al.Events[0].Type)
+		assert.Equal(t, "testuser", al.Events[0].Account)
+	}
+}
+
+func TestResetMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 2, MFASecret: testTOTPSecret, MFAEnabled: true, MFAUsedStep: 1}
+	rc := &mock.RecoveryCodeRepository{}
+	codes, _ := rc.Create(ctx, user.ID, 1)
+	al := &mock.AuditLogRepository{}
+	h := NewAdminUserHandler(&mock.UserRepository{User: user}, rc, &mock.MailVerificationRepository{}, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, al, &mock.Mailer{})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.DELETE("/v1/admin/users/:id/mfa", h.ResetMFA)
+
+	req, _ := http.NewRequest("DELETE", "/v1/admin/users/2/mfa", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.False(t, user.MFAEnabled)
+	assert.Empty(t, user.MFASecret)
+
+	e := entity.ManagedUser{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.False(t, e.MFAEnabled)
+
+	used, _ := rc.Use(ctx, user.ID, codes[0])
+	assert.False(t, used)
+
+	assert.Len(t, al.Events, 1)
+	assert.Equal(t, entity.AuditMFAReset, al.Events[0].Type)
+}
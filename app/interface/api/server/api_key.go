@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+type apiKeyHandler struct {
+	repo repository.APIKey
+}
+
+// NewAPIKeyHandler is create action handler for personal API key
+func NewAPIKeyHandler(kr repository.APIKey) handler.APIKey {
+	return &apiKeyHandler{
+		repo: kr,
+	}
+}
+
+// List is get API keys of authenticated user
+// @Summary Return API keys of authenticated user
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} entity.APIKey
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/api-keys [get]
+func (h *apiKeyHandler) List(c *gin.Context) {
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	keys, err := h.repo.FindAll(c.Request.Context(), user.ID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// Create is issue API key for authenticated user, the key is returned only at this time
+// @Summary Issue API key
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.CreateAPIKey true "request data"
+// @Success 201 {object} entity.IssuedAPIKey
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/api-keys [post]
+func (h *apiKeyHandler) Create(c *gin.Context) {
+	var p entity.CreateAPIKey
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	k := entity.APIKey{
+		UserID: user.ID,
+		Name:   p.Name,
+		Scope:  p.Scope,
+
+		TokenVersion: user.TokenVersion,
+	}
+	if p.ValidDays > 0 {
+		expiredAt := time.Now().AddDate(0, 0, p.ValidDays)
+		k.ExpiredAt = &expiredAt
+	}
+
+	key, err := h.repo.Create(c.Request.Context(), &k)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.IssuedAPIKey{
+		APIKey: k,
+		Key:    key,
+	})
+}
+
+// Delete is revoke API key of authenticated user
+// @Summary Revoke API key
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/api-keys/{id} [delete]
+func (h *apiKeyHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorNotFound(c, errAPIKeyNotFound)
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	// API key of other user is treated as not found
+	k, err := h.repo.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if k == nil || k.UserID != user.ID {
+		errorNotFound(c, errAPIKeyNotFound)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), k); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
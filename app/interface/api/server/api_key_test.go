@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	kr := &mock.APIKeyRepository{}
+	h := NewAPIKeyHandler(kr)
+
+	for _, body := range []string{`{}`, `{"name":"script","validDays":0.5}`, `{"name":"script","scope":"profile\tadmin"}`, `{"name":"script"}`, `{"name":"script","scope":"profile write"}`} {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/me/api-keys", h.Create)
+
+		req, _ := http.NewRequest("POST", "/v1/me/api-keys", bytes.NewBufferString(body))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1, TokenVersion: 2}))
+		r.POST("/v1/me/api-keys", h.Create)
+
+		req, _ := http.NewRequest("POST", "/v1/me/api-keys", bytes.NewBufferString(`{"name":"script","scope":"profile admin","validDays":30}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		e := entity.IssuedAPIKey{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.NotEmpty(t, e.Key)
+		assert.Equal(t, "script", e.Name)
+		assert.Equal(t, "profile admin", e.Scope)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *e.ExpiredAt, time.Minute)
+		assert.NotContains(t, w.Body.String(), "keyHash")
+
+		k, _ := kr.FindByKey(ctx, e.Key)
+		assert.Equal(t, uint(1), k.UserID)
+		assert.Equal(t, uint(2), k.TokenVersion)
+	}
+	{
+		// never expires without valid days
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/me/api-keys", h.Create)
+
+		req, _ := http.NewRequest("POST", "/v1/me/api-keys", bytes.NewBufferString(`{"name":"script","scope":"profile"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		e := entity.IssuedAPIKey{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Nil(t, e.ExpiredAt)
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	kr := &mock.APIKeyRepository{}
+	_, _ = kr.Create(ctx, &entity.APIKey{UserID: 1, Name: "first"})
+	_, _ = kr.Create(ctx, &entity.APIKey{UserID: 2, Name: "other"})
+	_, _ = kr.Create(ctx, &entity.APIKey{UserID: 1, Name: "second"})
+	h := NewAPIKeyHandler(kr)
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.Use(setIdentity(&entity.User{ID: 1}))
+	r.GET("/v1/me/api-keys", h.List)
+
+	req, _ := http.NewRequest("GET", "/v1/me/api-keys", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	e := []entity.APIKey{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, e, 2)
+	assert.Equal(t, "first", e[0].Name)
+	assert.Equal(t, "second", e[1].Name)
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	ctx := context.Background()
+	kr := &mock.APIKeyRepository{}
+	key, _ := kr.Create(ctx, &entity.APIKey{UserID: 1, Name: "script"})
+	h := NewAPIKeyHandler(kr)
+
+	{
+		// API key of other user
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 2}))
+		r.DELETE("/v1/me/api-keys/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/me/api-keys/1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.DELETE("/v1/me/api-keys/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/me/api-keys/1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		k, _ := kr.FindByKey(ctx, key)
+		assert.Nil(t, k)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Length of account recorded in audit event, it is input of the request for failed login
+	maxAuditAccountLength = 20
+	// Length of detail recorded in audit event
+	maxAuditDetailLength = 255
+)
+
+type auditHandler struct {
+	repo repository.AuditLog
+}
+
+// NewAuditHandler is create action handler for audit log
+func NewAuditHandler(ar repository.AuditLog) handler.Audit {
+	return &auditHandler{
+		repo: ar,
+	}
+}
+
+// List is get audit events with cursor pagination, newer events are first
+// @Summary Return audit events
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param cursor query int false "next cursor returned in previous page"
+// @Param limit query int false "number of events per page"
+// @Param type query string false "type of event" Enums(login.succeeded, login.failed, logout, user.registered, user.activated, password.changed, role.changed, password.expired, user.enabled, user.disabled, user.unlocked, mfa.reset, user.deleted)
+// @Param account query string false "account of user who was operated"
+// @Param actorId query int false "ID of user who operated"
+// @Param targetId query int false "ID of user who was operated"
+// @Param since query string false "events at or after the time (RFC 3339)"
+// @Param until query string false "events before the time (RFC 3339)"
+// @Success 200 {object} entity.AuditEvents
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/audit [get]
+func (h *auditHandler) List(c *gin.Context) {
+	var p entity.SearchAudit
+	if err := c.ShouldBindQuery(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	if p.Limit == 0 {
+		p.Limit = defaultLimit
+	}
+
+	// One more event is found for knowing whether next page exists
+	f := entity.AuditFilter{
+		Account:  p.Account,
+		ActorID:  p.ActorID,
+		TargetID: p.TargetID,
+		Since:    p.Since,
+		Until:    p.Until,
+		Cursor:   p.Cursor,
+		Limit:    p.Limit + 1,
+	}
+	if p.Type != nil {
+		t := entity.AuditEventType(*p.Type)
+		f.Type = &t
+	}
+
+	events, err := h.repo.FindAll(c.Request.Context(), f)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	res := entity.AuditEvents{Items: []entity.AuditEvent{}}
+	if len(events) > p.Limit {
+		events = events[:p.Limit]
+		res.NextCursor = events[len(events)-1].ID
+	}
+	res.Items = append(res.Items, events...)
+
+	c.JSON(http.StatusOK, res)
+}
+
+// Create audit event of operation to the target user by the actor, actor is nil if nobody is authenticated
+func auditEvent(t entity.AuditEventType, actor, target *entity.User, detail string) entity.AuditEvent {
+	e := entity.AuditEvent{
+		Type:   t,
+		Detail: detail,
+	}
+	if actor != nil {
+		id := actor.ID
+		e.ActorID = &id
+	}
+	if target != nil {
+		id := target.ID
+		e.TargetID = &id
+		e.Account = target.Account
+	}
+	return e
+}
+
+// Get the authenticated user operating, nil if not authenticated
+func actor(c *gin.Context) *entity.User {
+	identity, _ := c.Get(config.IdentityKey)
+	user, _ := identity.(*entity.User)
+	return user
+}
+
+// Record audit event with client of the request.
+// Failure is only logged, so that the operation is not interrupted by recording.
+func recordAudit(ar repository.AuditLog, c *gin.Context, e entity.AuditEvent) {
+	e.IPAddress = c.ClientIP()
+	e.UserAgent = userAgent(c)
+	if len(e.Account) > maxAuditAccountLength {
+		e.Account = strings.ToValidUTF8(e.Account[:maxAuditAccountLength], "")
+	}
+	if len(e.Detail) > maxAuditDetailLength {
+		e.Detail = strings.ToValidUTF8(e.Detail[:maxAuditDetailLength], "")
+	}
+	if err := ar.Create(c.Request.Context(), &e); err != nil {
+		log.Error().Err(err).Msg("")
+	}
+}
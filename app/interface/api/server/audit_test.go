@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func requestAudit(t *testing.T, r *gin.Engine, query string) (*httptest.ResponseRecorder, entity.AuditEvents) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/admin/audit"+query, nil)
+	r.ServeHTTP(w, req)
+
+	e := entity.AuditEvents{}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+	}
+	return w, e
+}
+
+func TestListAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	al := &mock.AuditLogRepository{}
+	for i := 0; i < 5; i++ {
+		target := &entity.User{ID: uint(i%2 + 1), Account: "testuser"}
+		al.Create(ctx, &entity.AuditEvent{Type: entity.AuditLoginSucceeded, TargetID: &target.ID})
+	}
+	al.Create(ctx, &entity.AuditEvent{Type: entity.AuditLoginFailed, Account: "unknown"})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.GET("/v1/admin/audit", NewAuditHandler(al).List)
+
+	{
+		w, _ := requestAudit(t, r, "?limit=1000")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = requestAudit(t, r, "?type=unknown")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	{
+		w, e := requestAudit(t, r, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, e.Items, 6)
+		assert.Equal(t, uint(6), e.Items[0].ID)
+		assert.Zero(t, e.NextCursor)
+	}
+	{
+		// cursor pagination
+		_, e := requestAudit(t, r, "?limit=4")
+		assert.Len(t, e.Items, 4)
+		assert.Equal(t, uint(3), e.NextCursor)
+
+		_, e = requestAudit(t, r, "?limit=4&cursor=3")
+		assert.Len(t, e.Items, 2)
+		assert.Equal(t, uint(2), e.Items[0].ID)
+		assert.Zero(t, e.NextCursor)
+	}
+	{
+		_, e := requestAudit(t, r, "?type=login.succeeded&targetId=2")
+		assert.Len(t, e.Items, 2)
+
+		_, e = requestAudit(t, r, "?account=unknown")
+		assert.Len(t, e.Items, 1)
+		assert.Equal(t, entity.AuditLoginFailed, e.Items[0].Type)
+
+		_, e = requestAudit(t, r, "?since=2100-01-01T00:00:00Z")
+		assert.Empty(t, e.Items)
+	}
+}
+
+func TestAuditLogin(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	al := &mock.AuditLogRepository{}
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
+		ID:       1,
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}}
+	d.Audit = al
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.DELETE("/v1/deauth", middleware.MiddlewareFunc(), middleware.LogoutHandler)
+
+	j, _ := json.Marshal(entity.Authenticate{Account: "testuser", Password: "invalidPassword1"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "192.0.2.1:1234"
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	c := login(t, r, "testuser", password)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/v1/deauth", nil)
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Len(t, al.Events, 3)
+
+	failed := al.Events[0]
+	assert.Equal(t, entity.AuditLoginFailed, failed.Type)
+	assert.Equal(t, "testuser", failed.Account)
+	assert.Equal(t, "192.0.2.1", failed.IPAddress)
+	assert.Equal(t, "test-agent", failed.UserAgent)
+	assert.Equal(t, errUnauthorized.Error(), failed.Detail)
+
+	assert.Equal(t, entity.AuditLoginSucceeded, al.Events[1].Type)
+	assert.Equal(t, uint(1), *al.Events[1].ActorID)
+	assert.Equal(t, uint(1), *al.Events[1].TargetID)
+	assert.Equal(t, entity.AuditLogout, al.Events[2].Type)
+}
+
+func TestRecordAuditTruncate(t *testing.T) {
+	al := &mock.AuditLogRepository{}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("POST", "/oauth/authorize", nil)
+
+	// unvalidated input is truncated to be recorded
+	recordAudit(al, c, entity.AuditEvent{
+		Type:    entity.AuditLoginFailed,
+		Account: strings.Repeat("a", 19) + "あ",
+		Detail:  strings.Repeat("b", 300),
+	})
+	if assert.Len(t, al.Events, 1) {
+		assert.Equal(t, strings.Repeat("a", 19), al.Events[0].Account)
+		assert.Len(t, al.Events[0].Detail, maxAuditDetailLength)
+	}
+}
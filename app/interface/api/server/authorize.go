@@ -0,0 +1,326 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authorizationCodeTimeout time.Duration = time.Minute
+	codeChallengeMethodS256                = "S256"
+)
+
+var (
+	// Code challenge of S256 is base64url encoded SHA-256 hash without padding (RFC 7636 section 4.2)
+	codeChallengeRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+	codeVerifierRegex  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+)
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in to {{.Client}}</title>
+<style>
+body { font-family: sans-serif; background: #f5f5f5; }
+main { max-width: 360px; margin: 48px auto; padding: 24px; background: #fff; border-radius: 8px; }
+label { display: block; margin: 12px 0; }
+input { display: block; width: 100%; box-sizing: border-box; padding: 8px; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<main>
+<h1>Sign in to {{.Client}}</h1>
+{{if .Scopes}}<p>{{.Client}} will be allowed to access:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+{{range $key, $value := .Params}}<input type="hidden" name="{{$key}}" value="{{$value}}">
+{{end}}<label>Account<input name="account" value="{{.Account}}" autocomplete="username" required></label>
+<label>Password<input type="password" name="password" autocomplete="current-password" required></label>
+<label>Authentication code (if enabled)<input name="code" autocomplete="one-time-code"></label>
+<button type="submit" name="approve" value="true">Allow</button>
+<button type="submit" name="approve" value="false" formnovalidate>Deny</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+var authorizeErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Authorization failed</title>
+</head>
+<body>
+<h1>Authorization failed</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+// Authorize is show login and consent page for authorization code flow with PKCE (RFC 6749, RFC 7636)
+// @Summary Show login and consent page of authorization code flow
+// @Tags OAuth
+// @Produce html
+// @Param response_type query string true "response type" Enums(code)
+// @Param client_id query string true "client ID"
+// @Param redirect_uri query string false "registered redirect URI, required if client has multiple URIs"
+// @Param scope query string false "space-delimited scope, all scopes of client if empty"
+// @Param state query string false "opaque value returned to client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "PKCE code challenge method" Enums(S256)
+// @Param nonce query string false "value included in ID token to mitigate replay attacks"
+// @Success 200
+// @Success 302
+// @Failure 400
+// @Router /oauth/authorize [get]
+func (h *oauthHandler) Authorize(c *gin.Context) {
+	var p entity.Authorize
+	if err := c.ShouldBindWith(&p, binding.Form); err != nil {
+		renderAuthorizeError(c, "request is invalid")
+		return
+	}
+
+	client, scope, ok := h.validateAuthorize(c, p)
+	if !ok {
+		return
+	}
+
+	renderConsent(c, http.StatusOK, client, p, scope, "", nil)
+}
+
+// Consent is authenticate user with submitted form and redirect to client with authorization code
+// @Summary Authenticate user and redirect with authorization code
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce html
+// @Param account formData string true "account"
+// @Param password formData string true "password"
+// @Param code formData string false "code of authenticator app or recovery code if two-factor authentication is enabled"
+// @Param approve formData bool true "whether user approved"
+// @Success 303
+// @Failure 400
+// @Failure 401
+// @Failure 429 {object} entity.Error
+// @Router /oauth/authorize [post]
+func (h *oauthHandler) Consent(c *gin.Context) {
+	var p entity.Consent
+	if err := c.ShouldBindWith(&p, binding.Form); err != nil {
+		renderAuthorizeError(c, "request is invalid")
+		return
+	}
+
+	client, scope, ok := h.validateAuthorize(c, p.Authorize)
+	if !ok {
+		return
+	}
+	redirectURI, _ := client.RedirectURI(p.RedirectURI)
+
+	if !p.Approve {
+		redirectAuthorize(c, redirectURI, p.State, url.Values{"error": {oauthAccessDenied}})
+		return
+	}
+
+	user, err := h.authenticate(c.Request.Context(), p.Account, p.Password)
+	if err != nil {
+		recordAudit(h.audit, c, entity.AuditEvent{
+			Type:    entity.AuditLoginFailed,
+			Account: p.Account,
+			Detail:  err.Error(),
+		})
+		renderConsent(c, http.StatusUnauthorized, client, p.Authorize, scope, p.Account, err)
+		return
+	}
+	if user.MFAEnabled {
+		if p.Code == "" {
+			renderConsent(c, http.StatusUnauthorized, client, p.Authorize, scope, p.Account, errMFARequired)
+			return
+		}
+
+		totp, recoveryCode := secondFactor(p.Code)
+		verified, err := h.verifySecondFactor(c.Request.Context(), user, totp, recoveryCode)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if !verified {
+			err := h.failLogin(c.Request.Context(), user)
+			if err != errAccountLocked {
+				err = errInvalidMFACode
+			}
+			recordAudit(h.audit, c, auditEvent(entity.AuditLoginFailed, user, user, err.Error()))
+			renderConsent(c, http.StatusUnauthorized, client, p.Authorize, scope, p.Account, err)
+			return
+		}
+		if user.FailedLogins > 0 || user.LockedUntil != nil {
+			if err := h.repo.Unlock(c.Request.Context(), user); err != nil {
+				errorInternalServerError(c, err)
+				return
+			}
+		}
+	}
+
+	code, err := h.codes.Create(c.Request.Context(), &entity.AuthorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   p.RedirectURI,
+		Scope:         scope,
+		CodeChallenge: p.CodeChallenge,
+		Nonce:         p.Nonce,
+		ExpiredAt:     h.TimeFunc().Add(authorizationCodeTimeout),
+	})
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditLoginSucceeded, user, user, "oauth "+client.ClientID))
+
+	redirectAuthorize(c, redirectURI, p.State, url.Values{"code": {code}})
+}
+
+// Validate authorization request and return client and granted scope, return false if response is already written.
+// Error is not redirected to client until redirect URI is verified (RFC 6749 section 4.1.2.1).
+func (h *oauthHandler) validateAuthorize(c *gin.Context, p entity.Authorize) (*entity.Client, string, bool) {
+	if p.ClientID == "" {
+		renderAuthorizeError(c, "client is invalid")
+		return nil, "", false
+	}
+	client, err := h.clients.FindByClientID(c.Request.Context(), p.ClientID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return nil, "", false
+	}
+	if client == nil || !client.IsEnable {
+		renderAuthorizeError(c, "client is invalid")
+		return nil, "", false
+	}
+	redirectURI, ok := client.RedirectURI(p.RedirectURI)
+	if !ok {
+		renderAuthorizeError(c, "redirect_uri is invalid")
+		return nil, "", false
+	}
+
+	if p.ResponseType != "code" {
+		redirectAuthorize(c, redirectURI, p.State, url.Values{"error": {oauthUnsupportedResponseType}})
+		return nil, "", false
+	}
+	if p.CodeChallengeMethod != codeChallengeMethodS256 || !codeChallengeRegex.MatchString(p.CodeChallenge) {
+		redirectAuthorize(c, redirectURI, p.State, url.Values{
+			"error":             {oauthInvalidRequest},
+			"error_description": {"code_challenge with S256 method is required"},
+		})
+		return nil, "", false
+	}
+	if len(p.Nonce) > maxNonceLength {
+		redirectAuthorize(c, redirectURI, p.State, url.Values{
+			"error":             {oauthInvalidRequest},
+			"error_description": {"nonce is too long"},
+		})
+		return nil, "", false
+	}
+	scope, ok := grantScope(client, p.Scope)
+	if !ok {
+		redirectAuthorize(c, redirectURI, p.State, url.Values{"error": {oauthInvalidScope}})
+		return nil, "", false
+	}
+
+	return client, scope, true
+}
+
+// Verify code verifier of PKCE matches to code challenge of S256 method
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if !codeVerifierRegex.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// Redirect to client with parameters of authorization response, state is returned as it is
+func redirectAuthorize(c *gin.Context, redirectURI, state string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+
+	code := http.StatusFound
+	if c.Request.Method == http.MethodPost {
+		code = http.StatusSeeOther
+	}
+	c.Redirect(code, u.String())
+}
+
+// Render login and consent page, parameters of authorization request are kept in hidden fields
+func renderConsent(c *gin.Context, code int, client *entity.Client, p entity.Authorize, scope string, account string, err error) {
+	params := map[string]string{
+		"response_type":         p.ResponseType,
+		"client_id":             p.ClientID,
+		"redirect_uri":          p.RedirectURI,
+		"scope":                 p.Scope,
+		"state":                 p.State,
+		"code_challenge":        p.CodeChallenge,
+		"code_challenge_method": p.CodeChallengeMethod,
+		"nonce":                 p.Nonce,
+	}
+	for key, value := range params {
+		if value == "" {
+			delete(params, key)
+		}
+	}
+
+	data := map[string]any{
+		"Client":  client.Name,
+		"Scopes":  (&entity.Client{Scope: scope}).Scopes(),
+		"Action":  c.Request.URL.Path,
+		"Params":  params,
+		"Account": account,
+		"Error":   "",
+	}
+	if err != nil {
+		data["Error"] = err.Error()
+	}
+	renderHTML(c, code, consentTemplate, data)
+}
+
+// Render error page of authorization request which can not be redirected to client
+func renderAuthorizeError(c *gin.Context, message string) {
+	renderHTML(c, http.StatusBadRequest, authorizeErrorTemplate, message)
+}
+
+// Render HTML page which is not allowed to be cached and embedded
+func renderHTML(c *gin.Context, code int, t *template.Template, data any) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	if err := t.Execute(c.Writer, data); err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	c.Abort()
+}
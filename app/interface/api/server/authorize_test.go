@@ -0,0 +1,437 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+// Code challenge of the verifier, from example of RFC 7636
+const testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+type testAuthorizeServer struct {
+	router  *gin.Engine
+	keys    *KeySet
+	user    *entity.User
+	client  entity.Client
+	clients *mock.ClientRepository
+	codes   *mock.AuthorizationCodeRepository
+}
+
+func newTestAuthorizeServer(t *testing.T) *testAuthorizeServer {
+	ctx := context.Background()
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), 10)
+	s := &testAuthorizeServer{
+		keys: newTestKeySet(t),
+		user: &entity.User{
+			ID:       1,
+			Account:  "testuser",
+			Password: string(cryptedPassword),
+			Role:     entity.RoleGeneral,
+			IsEnable: true,
+			IsActive: true,
+		},
+		client: entity.Client{
+			Name:         "SPA",
+			Scope:        "read write openid profile email",
+			Public:       true,
+			RedirectURIs: []string{"https://example.com/callback?app=1"},
+			IsEnable:     true,
+		},
+		clients: &mock.ClientRepository{},
+		codes:   &mock.AuthorizationCodeRepository{},
+	}
+	s.clients.Create(ctx, &s.client)
+
+	d := newTestAuthDeps(t)
+	d.Config = config.JWT{Issuer: "https://auth.example.com"}
+	d.Keys = s.keys
+	d.Users = &mock.UserRepository{User: s.user}
+	d.Clients = s.clients
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, s.router = gin.CreateTestContext(httptest.NewRecorder())
+	oh, err := NewOAuthHandler(d, s.clients, s.codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.router.GET("/oauth/authorize", oh.Authorize)
+	s.router.POST("/oauth/authorize", oh.Consent)
+	s.router.POST("/oauth/token", oh.Token)
+	s.router.GET("/oauth/userinfo", middleware.MiddlewareFunc(), NewOIDCHandler(d.Config, d.Keys).UserInfo)
+	s.router.POST("/v1/token/refresh", middleware.TokenRefreshHandler)
+	s.router.GET("/v1/me", middleware.MiddlewareFunc(), middleware.RequireScope(entity.ScopeProfile), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	s.router.GET("/v1/me/sessions", middleware.MiddlewareFunc(), middleware.RequireSession(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return s
+}
+
+func (s *testAuthorizeServer) params() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.client.ClientID},
+		"scope":                 {"read"},
+		"state":                 {"xyz"},
+		"code_challenge":        {testCodeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func (s *testAuthorizeServer) authorize(params url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/oauth/authorize?"+params.Encode(), nil)
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *testAuthorizeServer) consent(params url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// Approve with the credentials and return issued authorization code
+func (s *testAuthorizeServer) approve(t *testing.T) string {
+	return s.approveWith(t, s.params())
+}
+
+// Approve authorization request with the parameters and return issued authorization code
+func (s *testAuthorizeServer) approveWith(t *testing.T, params url.Values) string {
+	params.Set("account", "testuser")
+	params.Set("password", "password")
+	params.Set("approve", "true")
+	w := s.consent(params)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("approve failed: %d", w.Code)
+	}
+
+	u, _ := url.Parse(w.Header().Get("Location"))
+	return u.Query().Get("code")
+}
+
+func TestAuthorizePage(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+
+	w := s.authorize(s.params())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Contains(t, w.Body.String(), "Sign in to SPA")
+	assert.Contains(t, w.Body.String(), `<li>read</li>`)
+	assert.NotContains(t, w.Body.String(), `<li>write</li>`)
+	assert.Contains(t, w.Body.String(), `name="code_challenge" value="`+testCodeChallenge+`"`)
+
+	// state is escaped
+	params := s.params()
+	params.Set("state", `"><script>`)
+	w = s.authorize(params)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "<script>")
+}
+
+func TestAuthorizeInvalidRequest(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+
+	// not redirected to unverified URI
+	for key, value := range map[string]string{
+		"client_id":    "unknown",
+		"redirect_uri": "https://attacker.example.com/callback",
+	} {
+		params := s.params()
+		params.Set(key, value)
+		w := s.authorize(params)
+		assert.Equal(t, http.StatusBadRequest, w.Code, key)
+		assert.Empty(t, w.Header().Get("Location"), key)
+	}
+
+	// empty client ID does not match any client
+	params := s.params()
+	params.Set("client_id", "")
+	w := s.authorize(params)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	params.Set("account", "testuser")
+	params.Set("password", "password")
+	params.Set("approve", "true")
+	w = s.consent(params)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+
+	// redirected to client with error
+	for key, expected := range map[string]string{
+		"response_type":         oauthUnsupportedResponseType,
+		"code_challenge_method": oauthInvalidRequest,
+		"code_challenge":        oauthInvalidRequest,
+		"scope":                 oauthInvalidScope,
+	} {
+		params := s.params()
+		params.Set(key, "invalid")
+		w := s.authorize(params)
+		assert.Equal(t, http.StatusFound, w.Code, key)
+
+		u, _ := url.Parse(w.Header().Get("Location"))
+		assert.Equal(t, "example.com", u.Host)
+		assert.Equal(t, expected, u.Query().Get("error"), key)
+		assert.Equal(t, "xyz", u.Query().Get("state"), key)
+		assert.Equal(t, "1", u.Query().Get("app"), key)
+	}
+
+	// PKCE is mandatory
+	params = s.params()
+	params.Del("code_challenge")
+	params.Del("code_challenge_method")
+	w = s.authorize(params)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "error="+oauthInvalidRequest)
+}
+
+func TestAuthorizeConsent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuthorizeServer(t)
+
+	{
+		// denied by user
+		params := s.params()
+		params.Set("approve", "false")
+		w := s.consent(params)
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Contains(t, w.Header().Get("Location"), "error="+oauthAccessDenied)
+	}
+	{
+		params := s.params()
+		params.Set("account", "testuser")
+		params.Set("password", "invalid01")
+		params.Set("approve", "true")
+		w := s.consent(params)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), errUnauthorized.Error())
+		assert.Contains(t, w.Body.String(), `value="testuser"`)
+	}
+
+	code := s.approve(t)
+	assert.NotEmpty(t, code)
+
+	ac, _ := s.codes.FindByCode(ctx, code)
+	assert.Equal(t, s.client.ClientID, ac.ClientID)
+	assert.Equal(t, s.user.ID, ac.UserID)
+	assert.Equal(t, "read", ac.Scope)
+	assert.Equal(t, testCodeChallenge, ac.CodeChallenge)
+	assert.WithinDuration(t, time.Now().Add(authorizationCodeTimeout), ac.ExpiredAt, time.Second)
+}
+
+func TestAuthorizeConsentMFA(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+	s.user.MFASecret = testTOTPSecret
+	s.user.MFAEnabled = true
+
+	params := s.params()
+	params.Set("account", "testuser")
+	params.Set("password", "password")
+	params.Set("approve", "true")
+
+	w := s.consent(params)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), errMFARequired.Error())
+
+	params.Set("code", "000000")
+	w = s.consent(params)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), errInvalidMFACode.Error())
+
+	code, _ := totpCode(testTOTPSecret, time.Now().Unix()/totpPeriod)
+	params.Set("code", code)
+	w = s.consent(params)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "code=")
+}
+
+func TestAuthorizationCodeGrant(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+
+	exchange := func(code, verifier string) *httptest.ResponseRecorder {
+		return requestToken(s.router, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {s.client.ClientID},
+			"code":          {code},
+			"code_verifier": {verifier},
+		})
+	}
+
+	code := s.approve(t)
+
+	// verifier not matched
+	w := exchange(code, strings.Repeat("a", 43))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), oauthInvalidGrant)
+
+	// redirect URI not matched to authorization request
+	w = requestToken(s.router, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {s.client.ClientID},
+		"code":          {code},
+		"code_verifier": {testCodeVerifier},
+		"redirect_uri":  {s.client.RedirectURIs[0]},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = exchange(code, testCodeVerifier)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	e := entity.OAuthToken{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "Bearer", e.TokenType)
+	assert.Equal(t, "read", e.Scope)
+	assert.NotEmpty(t, e.RefreshToken)
+
+	token, err := gojwt.Parse(e.AccessToken, s.keys.KeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(gojwt.MapClaims)
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, s.client.ClientID, claims["client_id"])
+	assert.Equal(t, "read", claims["scope"])
+	assert.Equal(t, tokenTypeOAuth, claims[tokenTypeKey])
+
+	// token of the client is limited to the granted scope
+	request := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusForbidden, request("/v1/me", e.AccessToken).Code)
+	assert.Equal(t, http.StatusForbidden, request("/v1/me/sessions", e.AccessToken).Code)
+
+	// client and scope are kept by refreshing token
+	j, _ := json.Marshal(entity.Refresh{RefreshToken: e.RefreshToken})
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/token/refresh", bytes.NewBuffer(j))
+	req.Header.Set("Content-Type", "application/json")
+	s.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	refreshed := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Error(err)
+	}
+	token, err = gojwt.Parse(refreshed.Token, s.keys.KeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims = token.Claims.(gojwt.MapClaims)
+	assert.Equal(t, s.client.ClientID, claims["client_id"])
+	assert.Equal(t, "read", claims["scope"])
+	assert.Equal(t, tokenTypeOAuth, claims[tokenTypeKey])
+	assert.Equal(t, http.StatusForbidden, request("/v1/me", refreshed.Token).Code)
+
+	// code is used only once
+	w = exchange(code, testCodeVerifier)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), oauthInvalidGrant)
+
+	// public client is not allowed client credentials
+	w = requestToken(s.router, url.Values{"grant_type": {"client_credentials"}, "client_id": {s.client.ClientID}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), oauthUnauthorizedClient)
+}
+
+func TestAuthorizationCodeGrantClientDisabled(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+
+	params := s.params()
+	params.Set("scope", "openid")
+	w := requestToken(s.router, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {s.client.ClientID},
+		"code":          {s.approveWith(t, params)},
+		"code_verifier": {testCodeVerifier},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	e := entity.OAuthToken{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+
+	userInfo := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/oauth/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+e.AccessToken)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, userInfo())
+
+	// tokens issued to the client are rejected after it is disabled
+	s.client.IsEnable = false
+	if err := s.clients.Update(context.Background(), &s.client); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusUnauthorized, userInfo())
+
+	j, _ := json.Marshal(entity.Refresh{RefreshToken: e.RefreshToken})
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/token/refresh", bytes.NewBuffer(j))
+	req.Header.Set("Content-Type", "application/json")
+	s.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthorizationCodeExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuthorizeServer(t)
+	code, _ := s.codes.Create(ctx, &entity.AuthorizationCode{
+		ClientID:      s.client.ClientID,
+		UserID:        s.user.ID,
+		Scope:         "read",
+		CodeChallenge: testCodeChallenge,
+		ExpiredAt:     time.Now().Add(-time.Second),
+	})
+
+	w := requestToken(s.router, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {s.client.ClientID},
+		"code":          {code},
+		"code_verifier": {testCodeVerifier},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), oauthInvalidGrant)
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	assert.True(t, verifyCodeChallenge(testCodeChallenge, testCodeVerifier))
+	assert.False(t, verifyCodeChallenge(testCodeChallenge, testCodeVerifier+"a"))
+
+	// verifier shorter than 43 characters
+	sum := sha256.Sum256([]byte("short"))
+	assert.False(t, verifyCodeChallenge(base64.RawURLEncoding.EncodeToString(sum[:]), "short"))
+}
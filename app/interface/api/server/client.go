@@ -0,0 +1,250 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+type clientHandler struct {
+	repo repository.Client
+}
+
+// NewClientHandler is create action handler for OAuth client management
+func NewClientHandler(cr repository.Client) handler.Client {
+	return &clientHandler{
+		repo: cr,
+	}
+}
+
+// List is get OAuth clients
+// @Summary Return OAuth clients
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} entity.Client
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients [get]
+func (h *clientHandler) List(c *gin.Context) {
+	clients, err := h.repo.FindAll(c.Request.Context())
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// Get is get OAuth client
+// @Summary Return OAuth client
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "client ID"
+// @Success 200 {object} entity.Client
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients/{id} [get]
+func (h *clientHandler) Get(c *gin.Context) {
+	client, ok := h.findClient(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// Create is register OAuth client, the secret is returned only at this time.
+// Public client is not issued secret.
+// @Summary Register OAuth client
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.CreateClient true "request data"
+// @Success 201 {object} entity.IssuedClient
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients [post]
+func (h *clientHandler) Create(c *gin.Context) {
+	var p entity.CreateClient
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	client := entity.Client{
+		Name:         p.Name,
+		Scope:        p.Scope,
+		Public:       p.Public,
+		RedirectURIs: p.RedirectURIs,
+		IsEnable:     true,
+	}
+	secret, err := h.repo.Create(c.Request.Context(), &client)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if client.Public {
+		secret = ""
+	}
+
+	c.JSON(http.StatusCreated, entity.IssuedClient{
+		Client:       client,
+		ClientSecret: secret,
+	})
+}
+
+// Update is partial update of OAuth client
+// @Summary Update OAuth client partially
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param id path int true "client ID"
+// @Param data body entity.UpdateClient true "request data"
+// @Success 200 {object} entity.Client
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients/{id} [patch]
+func (h *clientHandler) Update(c *gin.Context) {
+	var p entity.UpdateClient
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	client, ok := h.findClient(c)
+	if !ok {
+		return
+	}
+
+	if p.Name != nil {
+		client.Name = *p.Name
+	}
+	if p.Scope != nil {
+		client.Scope = *p.Scope
+	}
+	if p.RedirectURIs != nil {
+		client.RedirectURIs = *p.RedirectURIs
+	}
+	if p.IsEnable != nil {
+		client.IsEnable = *p.IsEnable
+	}
+
+	if err := h.repo.Update(c.Request.Context(), client); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// RegenerateSecret is replace secret of OAuth client, the previous secret is no longer accepted
+// @Summary Regenerate secret of OAuth client
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "client ID"
+// @Success 200 {object} entity.IssuedClient
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients/{id}/secret [post]
+func (h *clientHandler) RegenerateSecret(c *gin.Context) {
+	client, ok := h.findClient(c)
+	if !ok {
+		return
+	}
+
+	if client.Public {
+		errorBadRequest(c, errPublicClient)
+		return
+	}
+
+	secret, err := h.repo.RegenerateSecret(c.Request.Context(), client)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.IssuedClient{
+		Client:       *client,
+		ClientSecret: secret,
+	})
+}
+
+// Delete is delete OAuth client
+// @Summary Delete OAuth client
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "client ID"
+// @Success 204
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/clients/{id} [delete]
+func (h *clientHandler) Delete(c *gin.Context) {
+	client, ok := h.findClient(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), client); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Find client from path parameter, return false if response is already written
+func (h *clientHandler) findClient(c *gin.Context) (*entity.Client, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorNotFound(c, errClientNotFound)
+		return nil, false
+	}
+
+	client, err := h.repo.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return nil, false
+	}
+	if client == nil {
+		errorNotFound(c, errClientNotFound)
+		return nil, false
+	}
+	return client, true
+}
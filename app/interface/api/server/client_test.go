@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateClient(t *testing.T) {
+	cr := &mock.ClientRepository{}
+	h := NewClientHandler(cr)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/admin/clients", h.Create)
+	r.GET("/v1/admin/clients", h.List)
+
+	w := postJSON(r, "/v1/admin/clients", entity.CreateClient{Name: "batch", Scope: "read  write"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	w = postJSON(r, "/v1/admin/clients", entity.CreateClient{Name: "batch", Scope: "read write"})
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	e := entity.IssuedClient{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.NotEmpty(t, e.ClientID)
+	assert.NotEmpty(t, e.ClientSecret)
+	assert.Equal(t, "read write", e.Scope)
+	assert.True(t, e.IsEnable)
+	assert.NotContains(t, w.Body.String(), "secretHash")
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/admin/clients", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	clients := []entity.Client{}
+	if err := json.Unmarshal(w.Body.Bytes(), &clients); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, clients, 1)
+	assert.NotContains(t, w.Body.String(), e.ClientSecret)
+}
+
+func TestUpdateClient(t *testing.T) {
+	ctx := context.Background()
+	cr := &mock.ClientRepository{}
+	client := entity.Client{Name: "batch", Scope: "read", IsEnable: true}
+	cr.Create(ctx, &client)
+	h := NewClientHandler(cr)
+
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.PATCH("/v1/admin/clients/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/clients/2", bytes.NewBufferString(`{"name":"other"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusNotFound)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.PATCH("/v1/admin/clients/:id", h.Update)
+
+		req, _ := http.NewRequest("PATCH", "/v1/admin/clients/1", bytes.NewBufferString(`{"scope":"read write","isEnable":false}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		stored, _ := cr.Find(ctx, 1)
+		assert.Equal(t, "batch", stored.Name)
+		assert.Equal(t, "read write", stored.Scope)
+		assert.False(t, stored.IsEnable)
+	}
+}
+
+func TestRegenerateClientSecret(t *testing.T) {
+	ctx := context.Background()
+	cr := &mock.ClientRepository{}
+	client := entity.Client{Name: "batch", IsEnable: true}
+	secret, _ := cr.Create(ctx, &client)
+	h := NewClientHandler(cr)
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.POST("/v1/admin/clients/:id/secret", h.RegenerateSecret)
+
+	req, _ := http.NewRequest("POST", "/v1/admin/clients/1/secret", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	e := entity.IssuedClient{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.NotEqual(t, secret, e.ClientSecret)
+
+	stored, _ := cr.Find(ctx, 1)
+	assert.NotNil(t, cr.MatchSecret(ctx, stored.SecretHash, secret))
+	assert.Nil(t, cr.MatchSecret(ctx, stored.SecretHash, e.ClientSecret))
+}
+
+func TestDeleteClient(t *testing.T) {
+	ctx := context.Background()
+	cr := &mock.ClientRepository{}
+	client := entity.Client{Name: "batch", IsEnable: true}
+	cr.Create(ctx, &client)
+	h := NewClientHandler(cr)
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.DELETE("/v1/admin/clients/:id", h.Delete)
+
+	req, _ := http.NewRequest("DELETE", "/v1/admin/clients/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	stored, _ := cr.Find(ctx, 1)
+	assert.Nil(t, stored)
+}
+
+func TestCreatePublicClient(t *testing.T) {
+	cr := &mock.ClientRepository{}
+	h := NewClientHandler(cr)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/admin/clients", h.Create)
+	r.POST("/v1/admin/clients/:id/secret", h.RegenerateSecret)
+
+	w := postJSON(r, "/v1/admin/clients", entity.CreateClient{Name: "spa", Public: true, RedirectURIs: []string{"/callback"}})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	w = postJSON(r, "/v1/admin/clients", entity.CreateClient{Name: "spa", Public: true, RedirectURIs: []string{"https://example.com/callback"}})
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	e := entity.IssuedClient{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.True(t, e.Public)
+	assert.Empty(t, e.ClientSecret)
+	assert.Equal(t, []string{"https://example.com/callback"}, e.RedirectURIs)
+
+	// public client does not have secret
+	w = postJSON(r, "/v1/admin/clients/1/secret", nil)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"math"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
@@ -16,12 +21,41 @@ const (
 )
 
 var (
-	errExistsAccount      = errors.New("account is already exists")
-	errInvalidAccount     = errors.New("account is invalid")
-	errMustChangePassword = errors.New("password must be changed")
-	errSamePassword       = errors.New("not allowed changing to same password")
-	errUnauthorized       = errors.New("authorization failed")
-	errValidationFailed   = errors.New("validation failed")
+	errAccountLocked            = errors.New("account is temporarily locked")
+	errAPIKeyNotFound           = errors.New("api key is not found")
+	errClientNotFound           = errors.New("client is not found")
+	errExistsAccount            = errors.New("account is already exists")
+	errInsufficientScope        = errors.New("token is not granted the scope")
+	errInvalidAccount           = errors.New("account is invalid")
+	errInvalidAPIKey            = errors.New("api key is invalid")
+	errInvalidAudience          = errors.New("token audience is invalid")
+	errInvalidClaims            = errors.New("token claims are invalid")
+	errInvalidChallenge         = errors.New("mfa challenge is invalid")
+	errInvalidInvitation        = errors.New("invitation code is invalid")
+	errInvalidMFACode           = errors.New("mfa code is invalid")
+	errInvalidIssuer            = errors.New("token issuer is invalid")
+	errInvalidRefreshToken      = errors.New("refresh token is invalid")
+	errInvalidResetToken        = errors.New("password reset token is invalid")
+	errInvalidVerificationToken = errors.New("mail verification token is invalid")
+	errInvitationNotFound       = errors.New("invitation is not found")
+	errMailNotVerified          = errors.New("mail address is not verified")
+	errMFAEnabled               = errors.New("mfa is already enabled")
+	errMFARequired              = errors.New("mfa code is required")
+	errMFANotEnabled            = errors.New("mfa is not enabled")
+	errMFANotEnrolled           = errors.New("mfa is not enrolled")
+	errMustChangePassword       = errors.New("password must be changed")
+	errOperateOwnAccount        = errors.New("not allowed operating own account")
+	errPasswordExpired          = errors.New("password is expired, change it with /v1/me/password")
+	errPermissionDenied         = errors.New("permission denied")
+	errPublicClient             = errors.New("public client does not have secret")
+	errRevokedToken             = errors.New("token is revoked")
+	errSamePassword             = errors.New("not allowed changing to same password")
+	errSessionNotFound          = errors.New("session is not found")
+	errSessionRequired          = errors.New("not allowed with api key or token of client")
+	errTooManyRequests          = errors.New("too many requests")
+	errUnauthorized             = errors.New("authorization failed")
+	errUserNotFound             = errors.New("user is not found")
+	errValidationFailed         = errors.New("validation failed")
 )
 
 // Return bad request response.
@@ -42,11 +76,44 @@ func errorUnauthorized(c *gin.Context, message any) {
 	})
 }
 
+// Return forbidden response.
+func errorForbidden(c *gin.Context, message any) {
+	errorJSON(c, entity.Error{
+		Code:    http.StatusForbidden,
+		Message: message,
+		Error:   nil,
+	})
+}
+
+// Return not found response.
+func errorNotFound(c *gin.Context, message any) {
+	errorJSON(c, entity.Error{
+		Code:    http.StatusNotFound,
+		Message: message,
+		Error:   nil,
+	})
+}
+
+// Return too many requests response with seconds to wait before retrying.
+func errorTooManyRequests(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	errorJSON(c, entity.Error{
+		Code:    http.StatusTooManyRequests,
+		Message: errTooManyRequests,
+		Error:   nil,
+	})
+}
+
 // Return internal server error response.
+// Error by exceeding deadline of the request is returned as service unavailable.
 func errorInternalServerError(c *gin.Context, err error) {
 	log.Error().Msgf("error: %s", err)
+	code := http.StatusInternalServerError
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = http.StatusServiceUnavailable
+	}
 	errorJSON(c, entity.Error{
-		Code:    http.StatusInternalServerError,
+		Code:    code,
 		Message: "",
 		Error:   err,
 	})
@@ -60,6 +127,8 @@ func errorJSON(c *gin.Context, err entity.Error) {
 		header = "error=\"invalid_request\""
 	case http.StatusUnauthorized:
 		header = "error=\"invalid_token\""
+	case http.StatusForbidden:
+		header = "error=\"insufficient_scope\""
 	}
 	if header != "" && c.GetString(TokenKey) != "" {
 		c.Writer.Header().Set("WWW-Authenticate", "Bearer "+header)
@@ -74,3 +143,12 @@ func errorJSON(c *gin.Context, err entity.Error) {
 	}
 	c.AbortWithStatusJSON(err.Code, err)
 }
+
+// Get user agent of the request, it is truncated to the length recorded
+func userAgent(c *gin.Context) string {
+	ua := c.Request.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLength], "")
+	}
+	return ua
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+type introspectHandler struct {
+	*jwtMiddleware
+	clients repository.Client
+}
+
+// NewIntrospectHandler is create action handler for token introspection,
+// tokens are verified in the same manner as the middleware created by NewAuthMiddleware with the dependencies.
+func NewIntrospectHandler(d AuthDeps, cr repository.Client) (handler.Introspect, error) {
+	mw, err := newJWTAuth(d).create()
+	if err != nil {
+		return nil, err
+	}
+	return &introspectHandler{
+		jwtMiddleware: mw,
+		clients:       cr,
+	}, nil
+}
+
+// Introspect is return state of the token for resource server (RFC 7662).
+// Caller is authenticated as confidential client in the same manner as token endpoint.
+// Token is inactive if it is expired, revoked, or the user or the client is no longer valid.
+// @Summary Return state of the token
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "token to introspect"
+// @Param token_type_hint formData string false "type of the token" Enums(access_token)
+// @Param client_id formData string false "client ID, if not using basic authentication"
+// @Param client_secret formData string false "client secret, if not using basic authentication"
+// @Success 200 {object} entity.Introspection
+// @Failure 400 {object} entity.OAuthError
+// @Failure 401 {object} entity.OAuthError
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /oauth/introspect [post]
+func (h *introspectHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var p entity.Introspect
+	if err := c.ShouldBindWith(&p, binding.Form); err != nil {
+		oauthError(c, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	client, ok := authenticateClient(c, h.clients, p.ClientID, p.ClientSecret)
+	if !ok {
+		return
+	}
+	// Public client can not keep credentials, so it is not allowed to know state of tokens
+	if client.Public {
+		oauthError(c, http.StatusUnauthorized, oauthInvalidClient, "public client is not allowed introspection")
+		return
+	}
+
+	res, err := h.introspect(c.Request.Context(), p.Token)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Get state of the access token, the state is consulted to the user, the client and revoked tokens
+func (h *introspectHandler) introspect(ctx context.Context, token string) (entity.Introspection, error) {
+	inactive := entity.Introspection{}
+
+	t, err := h.ParseTokenString(token)
+	if err != nil || !t.Valid {
+		return inactive, nil
+	}
+	claims := jwt.MapClaims(t.Claims.(gojwt.MapClaims))
+
+	res := entity.Introspection{Active: true, TokenType: "Bearer"}
+	res.Scope, _ = claims["scope"].(string)
+	res.ClientID, _ = claims["client_id"].(string)
+	res.Sub, _ = claims["sub"].(string)
+	res.Iss, _ = claims["iss"].(string)
+	res.Jti, _ = claims["jti"].(string)
+	if v, ok := claims["exp"].(float64); ok {
+		res.Exp = int64(v)
+	}
+	if v, ok := claims["iat"].(float64); ok {
+		res.Iat = int64(v)
+	}
+
+	if _, ok := claims[h.IdentityKey]; ok {
+		// Token of the user, including the user authorized the client
+		if err := h.verifyClaims(claims); err != nil {
+			return inactive, nil
+		}
+		user, err := h.repo.Find(ctx, uint(claims[h.IdentityKey].(float64)))
+		if err != nil {
+			return inactive, err
+		}
+		if user == nil || !user.Valid() {
+			return inactive, nil
+		}
+		if revoked, err := h.isRevoked(ctx, claims, user); err != nil {
+			return inactive, err
+		} else if revoked {
+			return inactive, nil
+		}
+		res.Username = user.Account
+		res.Role = string(user.Role)
+	} else {
+		// Token of client credentials, ID token is not access token and it has no client ID
+		if res.ClientID == "" || h.verifyRegisteredClaims(claims) != nil {
+			return inactive, nil
+		}
+		if res.Jti != "" {
+			if revoked, err := h.revoked.IsRevoked(ctx, res.Jti); err != nil {
+				return inactive, err
+			} else if revoked {
+				return inactive, nil
+			}
+		}
+	}
+
+	if res.ClientID != "" {
+		client, err := h.clients.FindByClientID(ctx, res.ClientID)
+		if err != nil {
+			return inactive, err
+		}
+		if client == nil || !client.IsEnable {
+			return inactive, nil
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestIntrospect(r *gin.Engine, form url.Values, basic ...string) (*httptest.ResponseRecorder, entity.Introspection) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(basic) == 2 {
+		req.SetBasicAuth(basic[0], basic[1])
+	}
+	r.ServeHTTP(w, req)
+
+	e := entity.Introspection{}
+	json.Unmarshal(w.Body.Bytes(), &e)
+	return w, e
+}
+
+func TestIntrospect(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", Role: entity.RoleGeneral, IsEnable: true}
+	rr := &mock.RevokedTokenRepository{}
+	cr := &mock.ClientRepository{}
+	rs := entity.Client{Name: "resource server", IsEnable: true}
+	rsSecret, _ := cr.Create(ctx, &rs)
+	batch := entity.Client{Name: "batch", Scope: "read", IsEnable: true}
+	batchSecret, _ := cr.Create(ctx, &batch)
+	spa := entity.Client{Name: "SPA", Public: true, IsEnable: true}
+	cr.Create(ctx, &spa)
+
+	d := newTestAuthDeps(t)
+	d.Config = config.JWT{Issuer: "https://auth.example.com"}
+	d.Users = &mock.UserRepository{User: user}
+	d.Revoked = rr
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := middleware.(*jwtMiddleware)
+
+	oh, err := NewOAuthHandler(d, cr, &mock.AuthorizationCodeRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ih, err := NewIntrospectHandler(d, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/oauth/token", oh.Token)
+	r.POST("/oauth/introspect", ih.Introspect)
+
+	token, _, err := mw.TokenGenerator(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		// not authenticated
+		w, _ := requestIntrospect(r, url.Values{"token": {token}})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = requestIntrospect(r, url.Values{"token": {token}, "client_id": {spa.ClientID}})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = requestIntrospect(r, url.Values{"token": {token}}, rs.ClientID, "invalid")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	{
+		w, _ := requestIntrospect(r, url.Values{}, rs.ClientID, rsSecret)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), oauthInvalidRequest)
+	}
+	{
+		w, e := requestIntrospect(r, url.Values{"token": {"invalid"}}, rs.ClientID, rsSecret)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"active":false}`, w.Body.String())
+		assert.False(t, e.Active)
+	}
+	{
+		w, e := requestIntrospect(r, url.Values{"token": {token}, "token_type_hint": {"access_token"}}, rs.ClientID, rsSecret)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.True(t, e.Active)
+		assert.Equal(t, "1", e.Sub)
+		assert.Equal(t, "testuser", e.Username)
+		assert.Equal(t, string(entity.RoleGeneral), e.Role)
+		assert.Equal(t, "https://auth.example.com", e.Iss)
+		assert.WithinDuration(t, time.Now().Add(timeout), time.Unix(e.Exp, 0), time.Minute)
+	}
+	{
+		// disabled user
+		user.IsEnable = false
+		_, e := requestIntrospect(r, url.Values{"token": {token}}, rs.ClientID, rsSecret)
+		assert.False(t, e.Active)
+		user.IsEnable = true
+
+		// revoked by revoking all sessions
+		user.TokenVersion++
+		_, e = requestIntrospect(r, url.Values{"token": {token}}, rs.ClientID, rsSecret)
+		assert.False(t, e.Active)
+		user.TokenVersion--
+
+		// revoked by logout
+		parsed, _ := gojwt.Parse(token, mw.keys.KeyFunc)
+		rr.Revoke(ctx, parsed.Claims.(gojwt.MapClaims)["jti"].(string), time.Now().Add(timeout))
+		_, e = requestIntrospect(r, url.Values{"token": {token}}, rs.ClientID, rsSecret)
+		assert.False(t, e.Active)
+	}
+	{
+		// token of client credentials
+		w := requestToken(r, url.Values{"grant_type": {"client_credentials"}}, batch.ClientID, batchSecret)
+		res := entity.OAuthToken{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Error(err)
+		}
+
+		_, e := requestIntrospect(r, url.Values{"token": {res.AccessToken}}, rs.ClientID, rsSecret)
+		assert.True(t, e.Active)
+		assert.Equal(t, batch.ClientID, e.ClientID)
+		assert.Equal(t, "read", e.Scope)
+		assert.Empty(t, e.Username)
+
+		// disabled client
+		batch.IsEnable = false
+		cr.Update(ctx, &batch)
+		_, e = requestIntrospect(r, url.Values{"token": {res.AccessToken}}, rs.ClientID, rsSecret)
+		assert.False(t, e.Active)
+	}
+}
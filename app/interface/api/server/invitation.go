@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+const (
+	defaultInvitationDays = 7
+)
+
+type invitationHandler struct {
+	repo repository.Invitation
+}
+
+// NewInvitationHandler is create action handler for invitation code
+func NewInvitationHandler(ir repository.Invitation) handler.Invitation {
+	return &invitationHandler{
+		repo: ir,
+	}
+}
+
+// List is get invitations
+// @Summary Return invitations
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} entity.Invitation
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/invitations [get]
+func (h *invitationHandler) List(c *gin.Context) {
+	invitations, err := h.repo.FindAll(c.Request.Context())
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// Create is issue single-use invitation code, the code is returned only at this time
+// @Summary Issue invitation code
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.CreateInvitation false "request data"
+// @Success 201 {object} entity.IssuedInvitation
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/invitations [post]
+func (h *invitationHandler) Create(c *gin.Context) {
+	var p entity.CreateInvitation
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&p); err != nil {
+			var verr validator.ValidationErrors
+			if errors.As(err, &verr) {
+				errorBadRequest(c, ValidationErrors(verr, &p))
+				return
+			}
+			errorBadRequest(c, errValidationFailed)
+			return
+		}
+	}
+	if p.ValidDays == 0 {
+		p.ValidDays = defaultInvitationDays
+	}
+
+	i := entity.Invitation{
+		ExpiredAt: time.Now().AddDate(0, 0, p.ValidDays),
+	}
+	identity, _ := c.Get(config.IdentityKey)
+	if v, ok := identity.(*entity.User); ok {
+		i.CreatedBy = v.ID
+	}
+
+	code, err := h.repo.Create(c.Request.Context(), &i)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entity.IssuedInvitation{
+		Invitation: i,
+		Code:       code,
+	})
+}
+
+// Delete is revoke invitation
+// @Summary Revoke invitation
+// @Tags Administration
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "invitation ID"
+// @Success 204
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/admin/invitations/{id} [delete]
+func (h *invitationHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorNotFound(c, errInvitationNotFound)
+		return
+	}
+
+	i, err := h.repo.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if i == nil {
+		errorNotFound(c, errInvitationNotFound)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), i); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateInvitation(t *testing.T) {
+	ctx := context.Background()
+	ir := &mock.InvitationRepository{}
+	h := NewInvitationHandler(ir)
+
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/admin/invitations", h.Create)
+
+		req, _ := http.NewRequest("POST", "/v1/admin/invitations", bytes.NewBufferString(`{"validDays":0.5}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/admin/invitations", h.Create)
+
+		req, _ := http.NewRequest("POST", "/v1/admin/invitations", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusCreated)
+
+		e := entity.IssuedInvitation{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.NotEmpty(t, e.Code)
+		assert.Equal(t, uint(1), e.CreatedBy)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, defaultInvitationDays), e.ExpiredAt, time.Minute)
+
+		// issued code is usable
+		ok, _ := ir.Use(ctx, e.Code)
+		assert.True(t, ok)
+	}
+}
+
+func TestListInvitations(t *testing.T) {
+	ctx := context.Background()
+	ir := &mock.InvitationRepository{}
+	_, _ = ir.Create(ctx, &entity.Invitation{ExpiredAt: time.Now().Add(time.Hour)})
+	_, _ = ir.Create(ctx, &entity.Invitation{ExpiredAt: time.Now().Add(time.Hour)})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+
+	h := NewInvitationHandler(ir)
+	r.GET("/v1/admin/invitations", h.List)
+
+	req, _ := http.NewRequest("GET", "/v1/admin/invitations", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	e := []entity.Invitation{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, e, 2)
+	assert.NotContains(t, w.Body.String(), "invitation-code")
+}
+
+func TestDeleteInvitation(t *testing.T) {
+	ctx := context.Background()
+	ir := &mock.InvitationRepository{}
+	code, _ := ir.Create(ctx, &entity.Invitation{ExpiredAt: time.Now().Add(time.Hour)})
+
+	h := NewInvitationHandler(ir)
+
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.DELETE("/v1/admin/invitations/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/admin/invitations/2", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusNotFound)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.DELETE("/v1/admin/invitations/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/admin/invitations/1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusNoContent)
+
+		// revoked code is not usable
+		ok, _ := ir.Use(ctx, code)
+		assert.False(t, ok)
+	}
+}
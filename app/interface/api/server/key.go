@@ -0,0 +1,343 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+var (
+	errInvalidKeyAlgorithm = errors.New("algorithm is not supported for the key")
+	errUnknownKey          = errors.New("token is signed with unknown key")
+)
+
+// signingKey is key for signing and verifying token
+type signingKey struct {
+	id     string
+	method gojwt.SigningMethod
+	// HMAC secret or private key
+	private any
+	// HMAC secret or public key
+	public any
+}
+
+// KeySet is set of keys for signing and verifying token
+type KeySet struct {
+	// Key for signing new token
+	current *signingKey
+	// Keys for verifying token, includes current key
+	keys []*signingKey
+}
+
+// NewKeySet is create key set from configuration
+func NewKeySet(c config.JWT) (*KeySet, error) {
+	var k *signingKey
+	var err error
+	if c.PrivateKeyFile != "" {
+		k, err = loadKey(c.PrivateKeyFile, c.Algorithm, true)
+	} else {
+		k, err = secretKey([]byte(c.SecretKey), c.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &KeySet{current: k}
+	s.add(k)
+
+	// Keys used before rotation, the algorithm falls back to default of the key type
+	for _, secret := range c.VerificationSecretKeys {
+		k, err := secretKey([]byte(secret), c.Algorithm)
+		if errors.Is(err, errInvalidKeyAlgorithm) {
+			k, err = secretKey([]byte(secret), "")
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.add(k)
+	}
+	for _, path := range c.VerificationKeyFiles {
+		k, err := loadKey(path, c.Algorithm, false)
+		if errors.Is(err, errInvalidKeyAlgorithm) {
+			k, err = loadKey(path, "", false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.add(k)
+	}
+
+	return s, nil
+}
+
+// Add key for verifying, the same key is ignored
+func (s *KeySet) add(k *signingKey) {
+	if s.find(k.id) == nil {
+		s.keys = append(s.keys, k)
+	}
+}
+
+// Find key from key ID
+func (s *KeySet) find(kid string) *signingKey {
+	for _, k := range s.keys {
+		if k.id == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// Algorithm is return signing algorithm of current key
+func (s *KeySet) Algorithm() string {
+	return s.current.method.Alg()
+}
+
+// Sign is create signed token with current key
+func (s *KeySet) Sign(claims gojwt.MapClaims) (string, error) {
+	t := gojwt.NewWithClaims(s.current.method, claims)
+	t.Header["kid"] = s.current.id
+	return t.SignedString(s.current.private)
+}
+
+// KeyFunc is return key for verifying token.
+// Token without key ID (issued before supporting rotation) is verified with current key.
+func (s *KeySet) KeyFunc(t *gojwt.Token) (any, error) {
+	k := s.current
+	if kid, ok := t.Header["kid"].(string); ok {
+		if k = s.find(kid); k == nil {
+			return nil, errUnknownKey
+		}
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, jwt.ErrInvalidSigningAlgorithm
+	}
+	return k.public, nil
+}
+
+// JWKSet is return public keys as JSON Web Key Set
+func (s *KeySet) JWKSet() entity.JWKSet {
+	set := entity.JWKSet{Keys: []entity.JWK{}}
+	for _, k := range s.keys {
+		if jwk, ok := publicJWK(k.public); ok {
+			jwk.Kid = k.id
+			jwk.Alg = k.method.Alg()
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// Create signing key from HMAC secret
+func secretKey(secret []byte, alg string) (*signingKey, error) {
+	if len(secret) == 0 {
+		return nil, jwt.ErrMissingSecretKey
+	}
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	m, ok := gojwt.GetSigningMethod(alg).(*gojwt.SigningMethodHMAC)
+	if !ok {
+		return nil, errInvalidKeyAlgorithm
+	}
+
+	// JWK thumbprint for symmetric key (RFC 7638 section 3.2)
+	h := sha256.Sum256([]byte(fmt.Sprintf(`{"k":"%s","kty":"oct"}`, base64.RawURLEncoding.EncodeToString(secret))))
+	return &signingKey{
+		id:      base64.RawURLEncoding.EncodeToString(h[:]),
+		method:  m,
+		private: secret,
+		public:  secret,
+	}, nil
+}
+
+// Create key from PEM file, the file for verifying may contain either public or private key
+func loadKey(path, alg string, signing bool) (*signingKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		if signing {
+			return nil, jwt.ErrInvalidPrivKey
+		}
+		return nil, jwt.ErrInvalidPubKey
+	}
+
+	var private crypto.Signer
+	var public crypto.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		private, err = parsePrivateKey(block)
+		if err == nil {
+			public = private.Public()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if signing && private == nil {
+		return nil, jwt.ErrInvalidPrivKey
+	}
+
+	m, err := signingMethod(public, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, _ := publicJWK(public)
+	return &signingKey{
+		id:      thumbprint(jwk),
+		method:  m,
+		private: private,
+		public:  public,
+	}, nil
+}
+
+// Parse private key from PEM block
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidPrivKey
+	}
+	return signer, nil
+}
+
+// Get signing method for public key, the default is decided from the key type
+func signingMethod(pub crypto.PublicKey, alg string) (gojwt.SigningMethod, error) {
+	var allowed []string
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		allowed = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().Name {
+		case "P-256":
+			allowed = []string{"ES256"}
+		case "P-384":
+			allowed = []string{"ES384"}
+		case "P-521":
+			allowed = []string{"ES512"}
+		}
+	case ed25519.PublicKey:
+		allowed = []string{"EdDSA"}
+	}
+	if len(allowed) == 0 {
+		return nil, jwt.ErrInvalidPrivKey
+	}
+
+	if alg == "" {
+		alg = allowed[0]
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return gojwt.GetSigningMethod(a), nil
+		}
+	}
+	return nil, errInvalidKeyAlgorithm
+}
+
+// Convert public key to JSON Web Key, return false if the key is not asymmetric
+func publicJWK(pub any) (entity.JWK, bool) {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return entity.JWK{
+			Kty: "RSA",
+			Use: "sig",
+			N:   enc(k.N.Bytes()),
+			E:   enc(big.NewInt(int64(k.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return entity.JWK{
+			Kty: "EC",
+			Use: "sig",
+			Crv: k.Curve.Params().Name,
+			X:   enc(k.X.FillBytes(make([]byte, size))),
+			Y:   enc(k.Y.FillBytes(make([]byte, size))),
+		}, true
+	case ed25519.PublicKey:
+		return entity.JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Crv: "Ed25519",
+			X:   enc(k),
+		}, true
+	}
+	return entity.JWK{}, false
+}
+
+// Get JWK thumbprint (RFC 7638) for using as key ID
+func thumbprint(k entity.JWK) string {
+	var s string
+	switch k.Kty {
+	case "RSA":
+		s = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, k.E, k.N)
+	case "EC":
+		s = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, k.Crv, k.X, k.Y)
+	case "OKP":
+		s = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, k.Crv, k.X)
+	}
+	h := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+type keyHandler struct {
+	keys *KeySet
+}
+
+// NewKeyHandler is create action handler for public keys
+func NewKeyHandler(ks *KeySet) handler.Key {
+	return &keyHandler{
+		keys: ks,
+	}
+}
+
+// JWKS is get public keys for verifying token
+// @Summary Return public keys for verifying token
+// @Tags Authenticate
+// @Produce json
+// @Success 200 {object} entity.JWKSet
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /.well-known/jwks.json [get]
+func (h *keyHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.keys.JWKSet())
+}
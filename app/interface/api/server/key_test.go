@@ -0,0 +1,216 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePrivateKey(t *testing.T, key any) string {
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "private.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewKeySetSecret(t *testing.T) {
+	_, err := NewKeySet(config.JWT{})
+	assert.NotNil(t, err)
+
+	_, err = NewKeySet(config.JWT{SecretKey: "secret", Algorithm: "RS256"})
+	assert.NotNil(t, err)
+
+	ks, err := NewKeySet(config.JWT{SecretKey: "secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, "HS256", ks.Algorithm())
+	assert.Empty(t, ks.JWKSet().Keys)
+}
+
+func TestNewKeySetPrivateKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := []struct {
+		key any
+		alg string
+		kty string
+	}{
+		{rsaKey, "RS256", "RSA"},
+		{ecKey, "ES256", "EC"},
+		{edKey, "EdDSA", "OKP"},
+	}
+	for _, c := range cases {
+		ks, err := NewKeySet(config.JWT{PrivateKeyFile: writePrivateKey(t, c.key)})
+		assert.Nil(t, err)
+		assert.Equal(t, c.alg, ks.Algorithm())
+
+		// sign and verify
+		s, err := ks.Sign(gojwt.MapClaims{"id": 1})
+		assert.Nil(t, err)
+		token, err := gojwt.Parse(s, ks.KeyFunc)
+		assert.Nil(t, err)
+		assert.True(t, token.Valid)
+
+		// public key
+		set := ks.JWKSet()
+		assert.Len(t, set.Keys, 1)
+		assert.Equal(t, c.kty, set.Keys[0].Kty)
+		assert.Equal(t, c.alg, set.Keys[0].Alg)
+		assert.Equal(t, token.Header["kid"], set.Keys[0].Kid)
+	}
+
+	// algorithm not matched to key
+	_, err := NewKeySet(config.JWT{PrivateKeyFile: writePrivateKey(t, ecKey), Algorithm: "RS256"})
+	assert.NotNil(t, err)
+
+	// file not exists
+	_, err = NewKeySet(config.JWT{PrivateKeyFile: filepath.Join(t.TempDir(), "none.pem")})
+	assert.NotNil(t, err)
+}
+
+func TestKeyFuncUnknownKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ks, err := NewKeySet(config.JWT{PrivateKeyFile: writePrivateKey(t, ecKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodES256, gojwt.MapClaims{"id": 1})
+	token.Header["kid"] = "unknown"
+	s, _ := token.SignedString(ecKey)
+	_, err = gojwt.Parse(s, ks.KeyFunc)
+	assert.NotNil(t, err)
+
+	// algorithm not matched to key
+	s, _ = gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{"id": 1}).SignedString([]byte("secret"))
+	_, err = gojwt.Parse(s, ks.KeyFunc)
+	assert.NotNil(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ks, err := NewKeySet(config.JWT{PrivateKeyFile: writePrivateKey(t, rsaKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+
+	h := NewKeyHandler(ks)
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	set := entity.JWKSet{}
+	if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, set.Keys, 1)
+	assert.Equal(t, "AQAB", set.Keys[0].E)
+	assert.NotEmpty(t, set.Keys[0].N)
+	assert.NotEmpty(t, set.Keys[0].Kid)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	oldPath := writePrivateKey(t, oldKey)
+
+	// token signed before rotation
+	old, err := NewKeySet(config.JWT{PrivateKeyFile: oldPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := old.Sign(gojwt.MapClaims{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify only with public key
+	b, _ := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	pubPath := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := NewKeySet(config.JWT{
+		PrivateKeyFile:       writePrivateKey(t, newKey),
+		VerificationKeyFiles: []string{pubPath, oldPath},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "ES256", ks.Algorithm())
+
+	token, err := gojwt.Parse(s, ks.KeyFunc)
+	assert.Nil(t, err)
+	assert.True(t, token.Valid)
+
+	// the same key is published once
+	set := ks.JWKSet()
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, "ES256", set.Keys[0].Alg)
+	assert.Equal(t, "RS256", set.Keys[1].Alg)
+
+	// new token is signed with current key
+	s, _ = ks.Sign(gojwt.MapClaims{"id": 1})
+	token, _ = gojwt.Parse(s, ks.KeyFunc)
+	assert.Equal(t, set.Keys[0].Kid, token.Header["kid"])
+
+	// public key can not be used for signing
+	_, err = NewKeySet(config.JWT{PrivateKeyFile: pubPath})
+	assert.NotNil(t, err)
+}
+
+func TestKeySetRotationSecret(t *testing.T) {
+	old, _ := NewKeySet(config.JWT{SecretKey: "old-secret"})
+	s, _ := old.Sign(gojwt.MapClaims{"id": 1})
+	legacy, _ := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{"id": 1}).SignedString([]byte("new-secret"))
+
+	ks, err := NewKeySet(config.JWT{
+		SecretKey:              "new-secret",
+		VerificationSecretKeys: []string{"old-secret"},
+	})
+	assert.Nil(t, err)
+
+	token, err := gojwt.Parse(s, ks.KeyFunc)
+	assert.Nil(t, err)
+	assert.True(t, token.Valid)
+
+	// token without key ID is verified with current key
+	token, err = gojwt.Parse(legacy, ks.KeyFunc)
+	assert.Nil(t, err)
+	assert.True(t, token.Valid)
+
+	// not rotated key
+	ks, _ = NewKeySet(config.JWT{SecretKey: "new-secret"})
+	_, err = gojwt.Parse(s, ks.KeyFunc)
+	assert.NotNil(t, err)
+
+	// secret is not published
+	assert.Empty(t, ks.JWKSet().Keys)
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	verificationTimeout time.Duration = time.Hour * 24
+)
+
+type mailHandler struct {
+	repo          repository.User
+	verifications repository.MailVerification
+	mailer        service.Mailer
+}
+
+// NewMailHandler is create action handler for verifying mail address
+func NewMailHandler(ur repository.User, vr repository.MailVerification, m service.Mailer) handler.Mail {
+	return &mailHandler{
+		repo:          ur,
+		verifications: vr,
+		mailer:        m,
+	}
+}
+
+// Verify is mark mail address of user as verified with token sent to the address
+// @Summary Verify mail address with mail verification token
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.VerifyMail true "request data"
+// @Success 200
+// @Failure 400 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/mail/verify [post]
+func (h *mailHandler) Verify(c *gin.Context) {
+	var p entity.VerifyMail
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	v, err := h.verifications.FindByToken(c.Request.Context(), p.Token)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if v == nil || v.UsedAt != nil || !v.ExpiredAt.After(time.Now()) {
+		errorBadRequest(c, errInvalidVerificationToken)
+		return
+	}
+
+	// Token sent to the previous address is not valid after the address is changed
+	user, err := h.repo.Find(c.Request.Context(), v.UserID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || user.MailAddress != v.MailAddress {
+		errorBadRequest(c, errInvalidVerificationToken)
+		return
+	}
+
+	if used, err := h.verifications.Use(c.Request.Context(), v); err != nil {
+		errorInternalServerError(c, err)
+		return
+	} else if !used {
+		errorBadRequest(c, errInvalidVerificationToken)
+		return
+	}
+
+	if !user.MailVerified() {
+		now := time.Now()
+		user.MailVerifiedAt = &now
+		if err := h.repo.Update(c.Request.Context(), user); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Resend is send mail verification token again to unverified mail address of user.
+// The response is the same whether user exists or not, so that account can not be guessed.
+// @Summary Send mail verification token again
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.ResendVerification true "request data"
+// @Success 202
+// @Failure 400 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/mail/verify/resend [post]
+func (h *mailHandler) Resend(c *gin.Context) {
+	var p entity.ResendVerification
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	users, err := findRecipients(c.Request.Context(), h.repo, p.Account, p.MailAddress)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	for i := range users {
+		if users[i].MailVerified() {
+			continue
+		}
+		if err := sendVerificationMail(c.Request.Context(), h.verifications, h.mailer, &users[i]); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// Issue token for verifying current mail address of the user and send it to the address.
+// Failure of delivery is only logged, so that the user is registered or updated anyway.
+func sendVerificationMail(ctx context.Context, vr repository.MailVerification, m service.Mailer, u *entity.User) error {
+	token, err := vr.Create(ctx, &entity.MailVerification{
+		UserID:      u.ID,
+		MailAddress: u.MailAddress,
+		ExpiredAt:   time.Now().Add(verificationTimeout),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.Send(verificationMail(u, token)); err != nil {
+		log.Error().Err(err).Msg("")
+	}
+	return nil
+}
+
+// Create mail for notifying mail verification token
+func verificationMail(u *entity.User, token string) entity.Mail {
+	return entity.Mail{
+		To:      u.MailAddress,
+		Subject: "Mail address verification",
+		Body: fmt.Sprintf(
+			"Hello %s,\r\n\r\nUse the following token to verify the mail address of account \"%s\".\r\nThe token expires in %d hours.\r\n\r\n%s\r\n\r\nIf you did not register it, please ignore this mail.\r\n",
+			u.Name, u.Account, int(verificationTimeout.Hours()), token,
+		),
+	}
+}
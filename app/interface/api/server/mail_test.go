@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestVerifyMail(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	j, _ := json.Marshal(entity.VerifyMail{Token: token})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/mail/verify", bytes.NewBuffer(j))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifyMail(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", Name: "Test User", MailAddress: "test@example.com"}
+	vr := &mock.MailVerificationRepository{}
+	ms := &mock.Mailer{}
+	h := NewMailHandler(&mock.UserRepository{User: user}, vr, ms)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/mail/verify", h.Verify)
+
+	if err := sendVerificationMail(ctx, vr, ms, user); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, ms.Mails, 1)
+	assert.Equal(t, "test@example.com", ms.Mails[0].To)
+	assert.Contains(t, ms.Mails[0].Body, "verification-token-1")
+
+	{
+		w := requestVerifyMail(r, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = requestVerifyMail(r, "invalid")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.False(t, user.MailVerified())
+	}
+	{
+		w := requestVerifyMail(r, "verification-token-1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, user.MailVerified())
+
+		// token is single-use
+		w = requestVerifyMail(r, "verification-token-1")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	{
+		// token sent to previous mail address
+		if err := sendVerificationMail(ctx, vr, ms, user); err != nil {
+			t.Fatal(err)
+		}
+		user.ChangeMailAddress("changed@example.com")
+
+		w := requestVerifyMail(r, "verification-token-2")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.False(t, user.MailVerified())
+	}
+	{
+		// expired token
+		token, _ := vr.Create(ctx, &entity.MailVerification{
+			UserID:      user.ID,
+			MailAddress: user.MailAddress,
+			ExpiredAt:   time.Now().Add(-time.Minute),
+		})
+
+		w := requestVerifyMail(r, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.False(t, user.MailVerified())
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", Name: "Test User", MailAddress: "test@example.com", IsEnable: true}
+	vr := &mock.MailVerificationRepository{}
+	ms := &mock.Mailer{}
+	h := NewMailHandler(&mock.UserRepository{User: user}, vr, ms)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/mail/verify/resend", h.Resend)
+
+	// account or mail address is required
+	w := postJSON(r, "/v1/mail/verify/resend", entity.ResendVerification{})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	// not matched user is not notified, but response is the same
+	w = postJSON(r, "/v1/mail/verify/resend", entity.ResendVerification{Account: "testuser", MailAddress: "other@example.com"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Empty(t, ms.Mails)
+
+	w = postJSON(r, "/v1/mail/verify/resend", entity.ResendVerification{Account: "testuser"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Len(t, ms.Mails, 1)
+	assert.Equal(t, "test@example.com", ms.Mails[0].To)
+	assert.Contains(t, ms.Mails[0].Body, "verification-token-1")
+
+	v, _ := vr.FindByToken(ctx, "verification-token-1")
+	assert.Equal(t, user.ID, v.UserID)
+	assert.Equal(t, user.MailAddress, v.MailAddress)
+
+	// verified mail address is not notified, but response is the same
+	now := time.Now()
+	user.MailVerifiedAt = &now
+	w = postJSON(r, "/v1/mail/verify/resend", entity.ResendVerification{MailAddress: "test@example.com"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Len(t, ms.Mails, 1)
+}
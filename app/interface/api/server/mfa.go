@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+const (
+	recoveryCodeCount = 10
+)
+
+type mfaHandler struct {
+	repo     repository.User
+	recovery repository.RecoveryCode
+	// Issuer displayed in authenticator app
+	issuer string
+	now    func() time.Time
+}
+
+// NewMFAHandler is create action handler for two-factor authentication
+func NewMFAHandler(ur repository.User, rc repository.RecoveryCode, issuer string) handler.MFA {
+	return &mfaHandler{
+		repo:     ur,
+		recovery: rc,
+		issuer:   issuer,
+		now:      time.Now,
+	}
+}
+
+// Enroll is generate TOTP secret for authenticated user, it is enabled after confirmed with code.
+// Secret not confirmed yet is replaced when enrolled again.
+// @Summary Generate TOTP secret for authenticated user
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} entity.MFAEnrollment
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/mfa [post]
+func (h *mfaHandler) Enroll(c *gin.Context) {
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+	if user.MFAEnabled {
+		errorBadRequest(c, errMFAEnabled)
+		return
+	}
+
+	secret, err := totpSecret()
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	user.MFASecret = secret
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.MFAEnrollment{
+		Secret: secret,
+		URI:    totpURI(h.issuer, user.Account, secret),
+	})
+}
+
+// Confirm is enable two-factor authentication with code of enrolled secret, and issue recovery codes
+// @Summary Enable two-factor authentication and issue recovery codes
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.VerifyMFA true "request data"
+// @Success 200 {object} entity.RecoveryCodes
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/mfa/confirm [post]
+func (h *mfaHandler) Confirm(c *gin.Context) {
+	var p entity.VerifyMFA
+	if !bindMFACode(c, &p) {
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+	if user.MFAEnabled {
+		errorBadRequest(c, errMFAEnabled)
+		return
+	}
+	if user.MFASecret == "" {
+		errorBadRequest(c, errMFANotEnrolled)
+		return
+	}
+
+	step, ok := verifyTOTP(user.MFASecret, p.Code, h.now())
+	if !ok {
+		errorBadRequest(c, errInvalidMFACode)
+		return
+	}
+
+	user.MFAEnabled = true
+	user.MFAUsedStep = step
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	codes, err := h.recovery.Create(c.Request.Context(), user.ID, recoveryCodeCount)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.RecoveryCodes{
+		Codes: codes,
+	})
+}
+
+// Disable is disable two-factor authentication of authenticated user with current code
+// @Summary Disable two-factor authentication of authenticated user
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.VerifyMFA true "request data"
+// @Success 204
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/mfa [delete]
+func (h *mfaHandler) Disable(c *gin.Context) {
+	var p entity.VerifyMFA
+	if !bindMFACode(c, &p) {
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+	if !user.MFAEnabled {
+		errorBadRequest(c, errMFANotEnabled)
+		return
+	}
+
+	if step, ok := verifyTOTP(user.MFASecret, p.Code, h.now()); !ok || step <= user.MFAUsedStep {
+		errorBadRequest(c, errInvalidMFACode)
+		return
+	}
+
+	user.ResetMFA()
+	if err := h.repo.Update(c.Request.Context(), user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.recovery.Delete(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Bind code of authenticator app, return false if response is already written
+func bindMFACode(c *gin.Context, p *entity.VerifyMFA) bool {
+	if err := c.ShouldBindJSON(p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, p))
+			return false
+		}
+		errorBadRequest(c, errValidationFailed)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// MFAHandler is issue token after verifying second factor for challenge token issued at login.
+// Either code of authenticator app or recovery code is accepted, failures are counted as failed login.
+// @Summary Verify second factor and issue token
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.AuthenticateMFA true "request data"
+// @Success 200 {object} entity.Claim
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/auth/mfa [post]
+func (mw *jwtMiddleware) MFAHandler(c *gin.Context) {
+	var p entity.AuthenticateMFA
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	t, err := mw.ParseTokenString(p.ChallengeToken)
+	if err != nil || !t.Valid {
+		errorUnauthorized(c, errInvalidChallenge)
+		return
+	}
+	claims := jwt.MapClaims(t.Claims.(gojwt.MapClaims))
+	id, err := mw.verifyChallenge(claims, tokenTypeMFAChallenge, mfaChallengeAudience)
+	if err != nil {
+		errorUnauthorized(c, errInvalidChallenge)
+		return
+	}
+
+	user, err := mw.repo.Find(c.Request.Context(), id)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || !user.Valid() || !user.MFAEnabled {
+		errorUnauthorized(c, errInvalidAccount)
+		return
+	}
+	if revoked, err := mw.isRevoked(c.Request.Context(), claims, user); err != nil {
+		errorInternalServerError(c, err)
+		return
+	} else if revoked {
+		errorUnauthorized(c, errInvalidChallenge)
+		return
+	}
+	if user.Locked(mw.TimeFunc()) {
+		errorUnauthorized(c, errAccountLocked)
+		return
+	}
+
+	verified, err := mw.verifySecondFactor(c.Request.Context(), user, p.Code, p.RecoveryCode)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if !verified {
+		err := mw.failLogin(c.Request.Context(), user)
+		if err != errAccountLocked {
+			err = errInvalidMFACode
+		}
+		recordAudit(mw.audit, c, auditEvent(entity.AuditLoginFailed, user, user, err.Error()))
+		errorUnauthorized(c, err)
+		return
+	}
+
+	// Challenge token is used only once
+	if jti, ok := claims["jti"].(string); ok {
+		exp, _ := claims["exp"].(float64)
+		if err := mw.revoked.Revoke(c.Request.Context(), jti, time.Unix(int64(exp), 0)); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := mw.repo.Unlock(c.Request.Context(), user); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+
+	// Password must be changed before token is issued
+	if err := mw.verifyPasswordState(user); err != nil {
+		mw.passwordChangeResponse(c, user, err)
+		return
+	}
+
+	token, expire, refreshToken, err := mw.startSession(c, user, "", "")
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(mw.audit, c, auditEvent(entity.AuditLoginSucceeded, user, user, "mfa"))
+
+	loginResponse(c, http.StatusOK, token, expire, refreshToken)
+}
+
+// Split code given in single field into code of authenticator app and recovery code by the length
+func secondFactor(code string) (string, string) {
+	if len(code) == totpDigits {
+		return code, ""
+	}
+	return "", code
+}
+
+// Verify code of authenticator app or recovery code, the code once used is not accepted again
+func (mw *jwtMiddleware) verifySecondFactor(ctx context.Context, user *entity.User, code, recoveryCode string) (bool, error) {
+	if code == "" {
+		return mw.recovery.Use(ctx, user.ID, recoveryCode)
+	}
+
+	step, ok := verifyTOTP(user.MFASecret, code, mw.TimeFunc())
+	if !ok || step <= user.MFAUsedStep {
+		return false, nil
+	}
+	user.MFAUsedStep = step
+	if err := mw.repo.Update(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Create handler with fixed clock
+func newTestMFAHandler(rc *mock.RecoveryCodeRepository, now time.Time) *mfaHandler {
+	h := NewMFAHandler(&mock.UserRepository{}, rc, "auth-api").(*mfaHandler)
+	h.now = func() time.Time { return now }
+	return h
+}
+
+func TestEnrollMFA(t *testing.T) {
+	h := newTestMFAHandler(&mock.RecoveryCodeRepository{}, time.Now())
+
+	{
+		user := &entity.User{ID: 1, Account: "testuser"}
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(user))
+		r.POST("/v1/me/mfa", h.Enroll)
+
+		req, _ := http.NewRequest("POST", "/v1/me/mfa", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		e := entity.MFAEnrollment{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, user.MFASecret, e.Secret)
+		assert.False(t, user.MFAEnabled)
+
+		u, _ := url.Parse(e.URI)
+		assert.Equal(t, "otpauth", u.Scheme)
+		assert.Equal(t, e.Secret, u.Query().Get("secret"))
+	}
+	{
+		// already enabled
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1, MFASecret: testTOTPSecret, MFAEnabled: true}))
+		r.POST("/v1/me/mfa", h.Enroll)
+
+		req, _ := http.NewRequest("POST", "/v1/me/mfa", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1111111109, 0)
+	rc := &mock.RecoveryCodeRepository{}
+	h := newTestMFAHandler(rc, now)
+	code, _ := totpCode(testTOTPSecret, now.Unix()/totpPeriod)
+
+	{
+		// not enrolled
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}))
+		r.POST("/v1/me/mfa/confirm", h.Confirm)
+
+		req, _ := http.NewRequest("POST", "/v1/me/mfa/confirm", bytes.NewBufferString(`{"code":"`+code+`"}`))
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		assert.Contains(t, w.Body.String(), errMFANotEnrolled.Error())
+	}
+
+	user := &entity.User{ID: 1, MFASecret: testTOTPSecret}
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.Use(setIdentity(user))
+	r.POST("/v1/me/mfa/confirm", h.Confirm)
+
+	w := postJSON(r, "/v1/me/mfa/confirm", entity.VerifyMFA{Code: "12345"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	w = postJSON(r, "/v1/me/mfa/confirm", entity.VerifyMFA{Code: "000000"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Contains(t, w.Body.String(), errInvalidMFACode.Error())
+	assert.False(t, user.MFAEnabled)
+
+	w = postJSON(r, "/v1/me/mfa/confirm", entity.VerifyMFA{Code: code})
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.True(t, user.MFAEnabled)
+	assert.Equal(t, now.Unix()/totpPeriod, user.MFAUsedStep)
+
+	e := entity.RecoveryCodes{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, e.Codes, recoveryCodeCount)
+	used, _ := rc.Use(ctx, user.ID, e.Codes[0])
+	assert.True(t, used)
+}
+
+func TestDisableMFA(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1111111109, 0)
+	rc := &mock.RecoveryCodeRepository{}
+	h := newTestMFAHandler(rc, now)
+	step := now.Unix() / totpPeriod
+	code, _ := totpCode(testTOTPSecret, step)
+
+	user := &entity.User{ID: 1, MFASecret: testTOTPSecret, MFAEnabled: true, MFAUsedStep: step}
+	codes, _ := rc.Create(ctx, user.ID, 1)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.Use(setIdentity(user))
+	r.DELETE("/v1/me/mfa", h.Disable)
+
+	deleteMFA := func(code string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/v1/me/mfa", bytes.NewBufferString(`{"code":"`+code+`"}`))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the code already used
+	assert.Equal(t, deleteMFA(code).Code, http.StatusBadRequest)
+	assert.True(t, user.MFAEnabled)
+
+	user.MFAUsedStep = step - 1
+	assert.Equal(t, deleteMFA(code).Code, http.StatusNoContent)
+	assert.False(t, user.MFAEnabled)
+	assert.Empty(t, user.MFASecret)
+
+	used, _ := rc.Use(ctx, user.ID, codes[0])
+	assert.False(t, used)
+
+	// not enabled
+	assert.Equal(t, deleteMFA(code).Code, http.StatusBadRequest)
+}
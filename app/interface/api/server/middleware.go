@@ -1,32 +1,109 @@
 package server
 
 import (
+	"context"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
 	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
 	"github.com/gotoeveryone/auth-api/app/presentation/middleware"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	timeout time.Duration = time.Hour * 2
+	timeout        time.Duration = time.Hour * 2
+	refreshTimeout time.Duration = time.Hour * 24 * 30
+	maxLockTimeout time.Duration = time.Hour * 24
+	mfaTimeout     time.Duration = time.Minute * 5
+	// Expiration of token only for changing password
+	passwordChangeTimeout time.Duration = time.Minute * 10
+	// Length of user agent recorded in session
+	maxUserAgentLength = 255
+	// Realm of authentication challenge
+	realm = "auth-api"
+	// Claim of token type, token issued by login of the user has no type and it is not limited by scope
+	tokenTypeKey = "typ"
+	// Type of claims authenticated with personal API key
+	tokenTypeAPIKey = "api_key"
+	// Type of token of the user issued to OAuth client
+	tokenTypeOAuth = "oauth"
+	// Type of challenge token waiting for second factor, it has own audience and no identity so that it is not accepted as access token
+	tokenTypeMFAChallenge = "mfa_challenge"
+	mfaChallengeAudience  = "auth-api:mfa_challenge"
+	// Type of token only for changing password issued at login of the user who must change password, it also has own audience and no identity
+	tokenTypePasswordChange = "password_change"
+	passwordChangeAudience  = "auth-api:password_change"
 )
 
+// AuthDeps is dependencies of middleware about auth
+type AuthDeps struct {
+	Config   config.JWT
+	Lockout  config.Lockout
+	Keys     *KeySet
+	Users    repository.User
+	Revoked  repository.RevokedToken
+	Refresh  repository.RefreshToken
+	Recovery repository.RecoveryCode
+	APIKeys  repository.APIKey
+	Sessions repository.Session
+	Clients  repository.Client
+	Audit    repository.AuditLog
+	Policy   *PasswordPolicy
+	// Whether user is not authenticated until the mail address is verified
+	RequireVerifiedMail bool
+}
+
 type jwtAuth struct {
-	repo repository.User
+	config   config.JWT
+	lockout  config.Lockout
+	keys     *KeySet
+	repo     repository.User
+	revoked  repository.RevokedToken
+	refresh  repository.RefreshToken
+	recovery repository.RecoveryCode
+	apiKeys  repository.APIKey
+	sessions repository.Session
+	clients  repository.Client
+	audit    repository.AuditLog
+	policy   *PasswordPolicy
+	// Whether user is not authenticated until the mail address is verified
+	requireVerifiedMail bool
+}
+
+// jwtMiddleware is gin-jwt middleware signing token with key set
+type jwtMiddleware struct {
+	*jwt.GinJWTMiddleware
+	jwtAuth
 }
 
 // NewAuthMiddleware is create middleware for auth
-func NewAuthMiddleware(ur repository.User) middleware.Auth {
+func NewAuthMiddleware(d AuthDeps) middleware.Auth {
+	return newJWTAuth(d)
+}
+
+func newJWTAuth(d AuthDeps) *jwtAuth {
 	return &jwtAuth{
-		repo: ur,
+		config:   d.Config,
+		lockout:  d.Lockout,
+		keys:     d.Keys,
+		repo:     d.Users,
+		revoked:  d.Revoked,
+		refresh:  d.Refresh,
+		recovery: d.Recovery,
+		apiKeys:  d.APIKeys,
+		sessions: d.Sessions,
+		clients:  d.Clients,
+		audit:    d.Audit,
+		policy:   d.Policy,
+
+		requireVerifiedMail: d.RequireVerifiedMail,
 	}
 }
 
@@ -34,14 +111,17 @@ func NewAuthMiddleware(ur repository.User) middleware.Auth {
 // @Tags Authenticate
 // @Produce json
 // @Param data body entity.Authenticate true "request data"
-// @Success 200 {object} entity.Claim
+// @Success 200 {object} entity.Claim "or entity.MFAChallenge if two-factor authentication is enabled"
+// @Failure 401 {object} entity.PasswordChangeRequired "or entity.Error if authentication failed"
 // @Failure 404 {object} entity.Error
 // @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
 // @Router /v1/auth [post]
-func loginResponse(c *gin.Context, code int, token string, expire time.Time) {
+func loginResponse(c *gin.Context, code int, token string, expire time.Time, refreshToken string) {
 	c.JSON(code, entity.Claim{
-		Token:  token,
-		Expire: expire.Format(time.RFC3339),
+		Token:        token,
+		Expire:       expire.Format(time.RFC3339),
+		RefreshToken: refreshToken,
 	})
 }
 
@@ -73,33 +153,50 @@ func logoutResponse(c *gin.Context, code int) {
 }
 
 // Create is create auth middleware
-func (m jwtAuth) Create() (*jwt.GinJWTMiddleware, error) {
+func (m jwtAuth) Create() (middleware.JWT, error) {
+	mw, err := m.create()
+	if err != nil {
+		return nil, err
+	}
+	return mw, nil
+}
+
+// Create gin-jwt middleware with the dependencies, handlers of OAuth also issue and verify token with it
+func (m jwtAuth) create() (*jwtMiddleware, error) {
 	identityKey := config.IdentityKey
-	middleware, err := jwt.New(&jwt.GinJWTMiddleware{
-		Realm:       "auth-api",
-		Key:         []byte(os.Getenv("SECRET_KEY")),
-		Timeout:     timeout,
-		MaxRefresh:  timeout,
-		IdentityKey: identityKey,
+	mw, err := jwt.New(&jwt.GinJWTMiddleware{
+		Realm:            realm,
+		SigningAlgorithm: m.keys.Algorithm(),
+		KeyFunc:          m.keys.KeyFunc,
+		Timeout:          timeout,
+		MaxRefresh:       timeout,
+		IdentityKey:      identityKey,
 		PayloadFunc: func(data any) jwt.MapClaims {
 			if v, ok := data.(*entity.User); ok {
 				return jwt.MapClaims{
-					identityKey: v.ID,
+					identityKey:            v.ID,
+					"sub":                  strconv.FormatUint(uint64(v.ID), 10),
+					config.RoleKey:         v.Role,
+					config.TokenVersionKey: v.TokenVersion,
 				}
 			}
 			return jwt.MapClaims{}
 		},
 		IdentityHandler: func(c *gin.Context) any {
 			claims := jwt.ExtractClaims(c)
-			key, ok := claims[identityKey]
+			key, ok := claims[identityKey].(float64)
 			if !ok {
 				return nil
 			}
-			user, err := m.repo.Find(uint(key.(float64)))
+			user, err := m.repo.Find(c.Request.Context(), uint(key))
 			if err != nil {
 				log.Error().Err(err).Msg("")
 				return nil
 			}
+			// Deleted or disabled user can not use issued token
+			if user == nil || !user.Valid() {
+				return nil
+			}
 			return user
 		},
 		Authenticator: func(c *gin.Context) (any, error) {
@@ -108,29 +205,18 @@ func (m jwtAuth) Create() (*jwt.GinJWTMiddleware, error) {
 				return nil, errUnauthorized
 			}
 
-			user, err := m.repo.FindByAccount(p.Account)
+			user, err := m.authenticate(c.Request.Context(), p.Account, p.Password)
 			if err != nil {
-				log.Error().Err(err).Msg("")
-				return nil, errUnauthorized
-			}
-
-			if user == nil {
-				return nil, errUnauthorized
-			}
-
-			if !user.Valid() {
-				return nil, errInvalidAccount
-			}
-
-			if !user.IsActive {
-				return nil, errMustChangePassword
-			}
-
-			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password)); err != nil {
-				log.Error().Err(err).Msg("")
-				return nil, errUnauthorized
+				recordAudit(m.audit, c, entity.AuditEvent{
+					Type:    entity.AuditLoginFailed,
+					Account: p.Account,
+					Detail:  err.Error(),
+				})
+				if user != nil {
+					return user, err
+				}
+				return nil, err
 			}
-
 			return user, nil
 		},
 		Authorizator: func(data any, c *gin.Context) bool {
@@ -156,7 +242,6 @@ func (m jwtAuth) Create() (*jwt.GinJWTMiddleware, error) {
 		TimeFunc: time.Now,
 
 		// Response
-		LoginResponse:   loginResponse,
 		LogoutResponse:  logoutResponse,
 		RefreshResponse: refreshResponse,
 	})
@@ -165,9 +250,498 @@ func (m jwtAuth) Create() (*jwt.GinJWTMiddleware, error) {
 		return nil, err
 	}
 
-	if err := middleware.MiddlewareInit(); err != nil {
+	if err := mw.MiddlewareInit(); err != nil {
 		return nil, err
 	}
 
-	return middleware, nil
+	return &jwtMiddleware{
+		GinJWTMiddleware: mw,
+		jwtAuth:          m,
+	}, nil
+}
+
+// Authenticate user with account and password.
+// The user who must change password is also returned with the error, so that only password is changed.
+func (m jwtAuth) authenticate(ctx context.Context, account, password string) (*entity.User, error) {
+	user, err := m.verifyPassword(ctx, account, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// It is checked after the password, so that verification state is not exposed to others
+	if m.requireVerifiedMail && !user.MailVerified() {
+		return nil, errMailNotVerified
+	}
+
+	// It is checked after the password, so that activation state and password age are not exposed to others
+	if err := m.verifyPasswordState(user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Return error if the user must change password before token is issued
+func (m jwtAuth) verifyPasswordState(user *entity.User) error {
+	if !user.IsActive {
+		return errMustChangePassword
+	}
+	if m.policy.Expired(user) {
+		return errPasswordExpired
+	}
+	return nil
+}
+
+// Verify password of the account, failures are counted and lock the account.
+// Activation state and password age are not checked, so that it is also used for activation.
+func (m jwtAuth) verifyPassword(ctx context.Context, account, password string) (*entity.User, error) {
+	user, err := m.repo.FindByAccount(ctx, account)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, errUnauthorized
+	}
+
+	if user == nil {
+		return nil, errUnauthorized
+	}
+
+	if !user.Valid() {
+		return nil, errInvalidAccount
+	}
+
+	// Locked account is not authenticated even if the password is correct
+	if user.Locked(time.Now()) {
+		return nil, errAccountLocked
+	}
+
+	if err := m.repo.MatchPassword(ctx, user.Password, password); err != nil {
+		log.Error().Err(err).Msg("")
+		return nil, m.failLogin(ctx, user)
+	}
+
+	// Failure is only logged, the password is rehashed at next login
+	if err := m.repo.Rehash(ctx, user, password); err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	// Failed logins are kept until second factor is verified, so that guessing code is also limited
+	if !user.MFAEnabled && (user.FailedLogins > 0 || user.LockedUntil != nil) {
+		if err := m.repo.Unlock(ctx, user); err != nil {
+			log.Error().Err(err).Msg("")
+			return nil, errUnauthorized
+		}
+	}
+
+	return user, nil
+}
+
+// Record failed login and lock account if failures reached to threshold, return error for response.
+// Locking duration is doubled at every failure after that, so guessing password takes longer.
+func (m jwtAuth) failLogin(ctx context.Context, user *entity.User) error {
+	if m.lockout.Threshold <= 0 {
+		return errUnauthorized
+	}
+	if err := m.repo.IncrementFailedLogins(ctx, user); err != nil {
+		log.Error().Err(err).Msg("")
+		return errUnauthorized
+	}
+
+	exceeded := int(user.FailedLogins) - m.lockout.Threshold
+	if exceeded < 0 {
+		return errUnauthorized
+	}
+	d := m.lockout.Duration
+	for i := 0; i < exceeded && d < maxLockTimeout; i++ {
+		d *= 2
+	}
+	if d > maxLockTimeout {
+		d = maxLockTimeout
+	}
+
+	if err := m.repo.Lock(ctx, user, time.Now().Add(d)); err != nil {
+		log.Error().Err(err).Msg("")
+		return errUnauthorized
+	}
+	return errAccountLocked
+}
+
+// MiddlewareFunc is verify token and set authenticated user, the revoked token is rejected.
+// Personal API key is also accepted in place of token.
+func (mw *jwtMiddleware) MiddlewareFunc() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := strings.TrimPrefix(c.GetHeader("Authorization"), mw.TokenHeadName+" "); strings.HasPrefix(key, config.APIKeyPrefix) {
+			mw.authenticateAPIKey(c, key)
+			return
+		}
+
+		claims, err := mw.GetClaimsFromJWT(c)
+		if err != nil {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+			return
+		}
+
+		if _, ok := claims["exp"]; !ok {
+			mw.unauthorized(c, http.StatusBadRequest, mw.HTTPStatusMessageFunc(jwt.ErrMissingExpField, c))
+			return
+		}
+
+		if err := mw.verifyClaims(claims); err != nil {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+			return
+		}
+
+		c.Set("JWT_PAYLOAD", claims)
+		identity := mw.IdentityHandler(c)
+		if identity == nil {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAccount, c))
+			return
+		}
+
+		revoked, err := mw.isRevoked(c.Request.Context(), claims, identity.(*entity.User))
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if revoked {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errRevokedToken, c))
+			return
+		}
+		if sid, ok := claims[config.SessionKey].(float64); ok {
+			if err := mw.sessions.Touch(c.Request.Context(), uint(sid)); err != nil {
+				log.Error().Err(err).Msg("")
+			}
+		}
+		c.Set(mw.IdentityKey, identity)
+
+		if !mw.Authorizator(identity, c) {
+			mw.unauthorized(c, http.StatusForbidden, mw.HTTPStatusMessageFunc(jwt.ErrForbidden, c))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Authenticate user with personal API key and record the time it is used.
+// Claims are the same as token of the user, with type and scope of the key.
+func (mw *jwtMiddleware) authenticateAPIKey(c *gin.Context, key string) {
+	k, err := mw.apiKeys.FindByKey(c.Request.Context(), key)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if k == nil || k.Expired(mw.TimeFunc()) {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAPIKey, c))
+		return
+	}
+
+	user, err := mw.repo.Find(c.Request.Context(), k.UserID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || !user.Valid() {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAccount, c))
+		return
+	}
+	// Key issued before changing password or revoking all sessions is revoked
+	if k.TokenVersion != user.TokenVersion {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAPIKey, c))
+		return
+	}
+
+	if err := mw.apiKeys.Touch(c.Request.Context(), k); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	claims := mw.PayloadFunc(user)
+	claims[tokenTypeKey] = tokenTypeAPIKey
+	claims["scope"] = k.Scope
+	c.Set("JWT_PAYLOAD", claims)
+	c.Set(mw.IdentityKey, user)
+
+	if !mw.Authorizator(user, c) {
+		mw.unauthorized(c, http.StatusForbidden, mw.HTTPStatusMessageFunc(jwt.ErrForbidden, c))
+		return
+	}
+
+	c.Next()
+}
+
+// RequireRole is allow access only for authenticated user having any of the roles
+func (mw *jwtMiddleware) RequireRole(roles ...entity.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := c.Get(mw.IdentityKey)
+		if user, ok := identity.(*entity.User); ok {
+			for _, r := range roles {
+				if user.Role == r {
+					c.Next()
+					return
+				}
+			}
+		}
+		errorForbidden(c, errPermissionDenied)
+	}
+}
+
+// RequireScope is allow access only for token granted the scope, token issued by login of the user is allowed any scope
+func (mw *jwtMiddleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := jwt.ExtractClaims(c)
+		if limited(claims) {
+			granted, _ := claims["scope"].(string)
+			if !hasScope(granted, scope) {
+				errorForbidden(c, errInsufficientScope)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession is allow access only for token issued by login of the user.
+// Credentials of the user are not managed with personal API key or token issued to OAuth client.
+func (mw *jwtMiddleware) RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limited(jwt.ExtractClaims(c)) {
+			errorForbidden(c, errSessionRequired)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Whether claims are limited by scope, that is, not issued by login of the user.
+// Token of OAuth client issued before typing token has only client ID.
+// Token only for changing password is issued by login, and it is not accepted by other routes.
+func limited(claims jwt.MapClaims) bool {
+	typ, typed := claims[tokenTypeKey]
+	_, client := claims["client_id"]
+	return (typed && typ != tokenTypePasswordChange) || client
+}
+
+// Check token is revoked by logout or by revoking all sessions of the user.
+// Token version of the user is incremented for revoking all sessions.
+// Token authorized for OAuth client is also revoked when the client is disabled or deleted.
+func (mw *jwtMiddleware) isRevoked(ctx context.Context, claims jwt.MapClaims, user *entity.User) (bool, error) {
+	// Token issued before supporting session version has no version, it is the same as initial version
+	ver, _ := claims[config.TokenVersionKey].(float64)
+	if uint(ver) != user.TokenVersion {
+		return true, nil
+	}
+	// Token issued before supporting session has no session
+	if sid, ok := claims[config.SessionKey].(float64); ok {
+		session, err := mw.sessions.Find(ctx, uint(sid))
+		if err != nil {
+			return false, err
+		}
+		if session == nil || session.UserID != user.ID || !session.Active(mw.TimeFunc()) {
+			return true, nil
+		}
+	}
+	if clientID, ok := claims["client_id"].(string); ok {
+		if enabled, err := mw.clientEnabled(ctx, clientID); err != nil || !enabled {
+			return !enabled, err
+		}
+	}
+	if jti, ok := claims["jti"].(string); ok {
+		return mw.revoked.IsRevoked(ctx, jti)
+	}
+	return false, nil
+}
+
+// Check the OAuth client is registered and enabled
+func (mw *jwtMiddleware) clientEnabled(ctx context.Context, clientID string) (bool, error) {
+	client, err := mw.clients.FindByClientID(ctx, clientID)
+	if err != nil {
+		return false, err
+	}
+	return client != nil && client.IsEnable, nil
+}
+
+// Verify registered claims and type of claims of access token, challenge token is not accepted
+func (mw *jwtMiddleware) verifyClaims(claims jwt.MapClaims) error {
+	if err := mw.verifyRegisteredClaims(claims); err != nil {
+		return err
+	}
+
+	if _, ok := claims[mw.IdentityKey].(float64); !ok {
+		return errInvalidClaims
+	}
+	for _, key := range []string{"sub", "jti"} {
+		if v, ok := claims[key]; ok {
+			if _, ok := v.(string); !ok {
+				return errInvalidClaims
+			}
+		}
+	}
+	// Only token of the user, including the one issued to OAuth client, is access token
+	if typ, ok := claims[tokenTypeKey]; ok && typ != tokenTypeOAuth {
+		return errInvalidClaims
+	}
+	return nil
+}
+
+// Verify claims of token issued at login for the next step of the type, return ID of the user
+func (mw *jwtMiddleware) verifyChallenge(claims jwt.MapClaims, typ, audience string) (uint, error) {
+	mc := gojwt.MapClaims(claims)
+	if mw.config.Issuer != "" && !mc.VerifyIssuer(mw.config.Issuer, true) {
+		return 0, errInvalidIssuer
+	}
+	if !mc.VerifyAudience(audience, true) {
+		return 0, errInvalidAudience
+	}
+	if claims[tokenTypeKey] != typ {
+		return 0, errInvalidClaims
+	}
+	sub, _ := claims["sub"].(string)
+	id, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil {
+		return 0, errInvalidClaims
+	}
+	return uint(id), nil
+}
+
+// Verify issuer and audience if they are configured
+func (mw *jwtMiddleware) verifyRegisteredClaims(claims jwt.MapClaims) error {
+	mc := gojwt.MapClaims(claims)
+	if mw.config.Issuer != "" && !mc.VerifyIssuer(mw.config.Issuer, true) {
+		return errInvalidIssuer
+	}
+	if mw.config.Audience != "" && !mc.VerifyAudience(mw.config.Audience, true) {
+		return errInvalidAudience
+	}
+	return nil
+}
+
+// LogoutHandler is revoke the authenticated token
+func (mw *jwtMiddleware) LogoutHandler(c *gin.Context) {
+	claims := jwt.ExtractClaims(c)
+	if jti, ok := claims["jti"].(string); ok {
+		exp, _ := claims["exp"].(float64)
+		if err := mw.revoked.Revoke(c.Request.Context(), jti, time.Unix(int64(exp), 0)); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+	if sid, ok := claims[config.SessionKey].(float64); ok {
+		session, err := mw.sessions.Find(c.Request.Context(), uint(sid))
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if session != nil {
+			if err := revokeSession(c.Request.Context(), mw.sessions, mw.refresh, session); err != nil {
+				errorInternalServerError(c, err)
+				return
+			}
+		}
+	}
+
+	identity, _ := c.Get(mw.IdentityKey)
+	if user, ok := identity.(*entity.User); ok {
+		recordAudit(mw.audit, c, auditEvent(entity.AuditLogout, user, user, ""))
+	}
+
+	mw.GinJWTMiddleware.LogoutHandler(c)
+}
+
+// LoginHandler is issue token signed with key set for authenticated user
+func (mw *jwtMiddleware) LoginHandler(c *gin.Context) {
+	data, err := mw.Authenticator(c)
+	user, _ := data.(*entity.User)
+	if user == nil {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	// Token is not issued until second factor is verified
+	if user.MFAEnabled {
+		claims := gojwt.MapClaims{
+			"sub":                  strconv.FormatUint(uint64(user.ID), 10),
+			"aud":                  mfaChallengeAudience,
+			tokenTypeKey:           tokenTypeMFAChallenge,
+			config.TokenVersionKey: user.TokenVersion,
+		}
+		token, expire, err := mw.sign(claims, mfaTimeout)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, entity.MFAChallenge{
+			MFARequired:    true,
+			ChallengeToken: token,
+			Expire:         expire.Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Password must be changed before token is issued
+	if err != nil {
+		mw.passwordChangeResponse(c, user, err)
+		return
+	}
+
+	token, expire, refreshToken, err := mw.startSession(c, user, "", "")
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(mw.audit, c, auditEvent(entity.AuditLoginSucceeded, user, user, ""))
+
+	loginResponse(c, http.StatusOK, token, expire, refreshToken)
+}
+
+// RefreshHandler is reissue token signed with key set from valid token
+func (mw *jwtMiddleware) RefreshHandler(c *gin.Context) {
+	claims, err := mw.CheckIfTokenExpire(c)
+	if err != nil {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	if err := mw.verifyClaims(jwt.MapClaims(claims)); err != nil {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	c.Set("JWT_PAYLOAD", jwt.MapClaims(claims))
+	identity := mw.IdentityHandler(c)
+	if identity == nil {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAccount, c))
+		return
+	}
+	revoked, err := mw.isRevoked(c.Request.Context(), jwt.MapClaims(claims), identity.(*entity.User))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if revoked {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errRevokedToken, c))
+		return
+	}
+	if err := mw.verifyPasswordState(identity.(*entity.User)); err != nil {
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	token, expire, err := mw.sign(claims, mw.Timeout)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+		mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(jwt.ErrFailedTokenCreation, c))
+		return
+	}
+
+	mw.RefreshResponse(c, http.StatusOK, token, expire)
+}
+
+// Return unauthorized response in the same manner as gin-jwt.
+func (mw *jwtMiddleware) unauthorized(c *gin.Context, code int, message string) {
+	c.Header("WWW-Authenticate", "JWT realm="+mw.Realm)
+	if !mw.DisabledAbort {
+		c.Abort()
+	}
+	mw.Unauthorized(c, code, message)
 }
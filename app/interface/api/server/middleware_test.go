@@ -2,23 +2,55 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
 	"github.com/gotoeveryone/auth-api/app/mock"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
 
+func newTestKeySet(t *testing.T) *KeySet {
+	ks, err := NewKeySet(config.JWT{SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// Create dependencies of auth middleware whose repositories are empty
+func newTestAuthDeps(t *testing.T) AuthDeps {
+	return AuthDeps{
+		Keys:     newTestKeySet(t),
+		Users:    &mock.UserRepository{},
+		Revoked:  &mock.RevokedTokenRepository{},
+		Refresh:  &mock.RefreshTokenRepository{},
+		Recovery: &mock.RecoveryCodeRepository{},
+		APIKeys:  &mock.APIKeyRepository{},
+		Sessions: &mock.SessionRepository{},
+		Clients:  &mock.ClientRepository{},
+		Audit:    &mock.AuditLogRepository{},
+		Policy:   newTestPasswordPolicy(),
+	}
+}
+
 func TestLoginFailedInvalidParam(t *testing.T) {
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
 
-	m := NewAuthMiddleware(&mock.UserRepository{})
+	m := NewAuthMiddleware(newTestAuthDeps(t))
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -43,7 +75,7 @@ func TestLoginFailedAccountNotExist(t *testing.T) {
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
 
-	m := NewAuthMiddleware(&mock.UserRepository{})
+	m := NewAuthMiddleware(newTestAuthDeps(t))
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -72,11 +104,13 @@ func TestLoginFailedInvalidAccount(t *testing.T) {
 	_, r := gin.CreateTestContext(w)
 
 	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), 10)
-	m := NewAuthMiddleware(&mock.UserRepository{User: &entity.User{
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
 		Account:  "testuser",
 		Password: string(cryptedPassword),
 		IsEnable: false,
-	}})
+	}}
+	m := NewAuthMiddleware(d)
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -105,12 +139,14 @@ func TestLoginFaileNotActiveAccount(t *testing.T) {
 	_, r := gin.CreateTestContext(w)
 
 	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), 10)
-	m := NewAuthMiddleware(&mock.UserRepository{User: &entity.User{
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
 		Account:  "testuser",
 		Password: string(cryptedPassword),
 		IsEnable: true,
 		IsActive: false,
-	}})
+	}}
+	m := NewAuthMiddleware(d)
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -139,12 +175,14 @@ func TestLoginFailedPasswordNotMatched(t *testing.T) {
 	_, r := gin.CreateTestContext(w)
 
 	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), 10)
-	m := NewAuthMiddleware(&mock.UserRepository{User: &entity.User{
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
 		Account:  "testuser",
 		Password: string(cryptedPassword),
 		IsEnable: true,
 		IsActive: true,
-	}})
+	}}
+	m := NewAuthMiddleware(d)
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -168,18 +206,211 @@ func TestLoginFailedPasswordNotMatched(t *testing.T) {
 	assert.Equal(t, w.Code, http.StatusUnauthorized)
 }
 
+func TestLoginMailNotVerified(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	user := &entity.User{
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: user}
+	d.RequireVerifiedMail = true
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+
+	request := func(password string) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(entity.Authenticate{
+			Account:  "testuser",
+			Password: password,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// verification state is not exposed without correct password
+	w := request("invalidPassword1")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotContains(t, w.Body.String(), errMailNotVerified.Error())
+
+	w = request(password)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), errMailNotVerified.Error())
+
+	now := time.Now()
+	user.MailVerifiedAt = &now
+	login(t, r, "testuser", password)
+}
+
+func TestLoginPasswordExpired(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	changed := time.Now().Add(-91 * 24 * time.Hour)
+	user := &entity.User{
+		Account:           "testuser",
+		Password:          string(cryptedPassword),
+		PasswordChangedAt: &changed,
+		IsEnable:          true,
+		IsActive:          true,
+	}
+	ur := &mock.UserRepository{User: user}
+	policy := NewPasswordPolicy(config.PasswordPolicy{MaxAge: 90 * 24 * time.Hour}, ur, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	d.Policy = policy
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.GET("/v1/me", middleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	r.PUT("/v1/me/password", middleware.PasswordChangeFunc(), middleware.RequireSession(), middleware.ChangePasswordHandler)
+
+	request := func(password string) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(entity.Authenticate{
+			Account:  "testuser",
+			Password: password,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	requestWithToken := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(j))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// password age is not exposed without correct password
+	w := request("invalidPassword1")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotContains(t, w.Body.String(), errPasswordExpired.Error())
+	assert.NotContains(t, w.Body.String(), "passwordChangeToken")
+
+	w = request(password)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), errPasswordExpired.Error())
+
+	e := entity.PasswordChangeRequired{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, e.PasswordChangeToken)
+
+	// token only for changing password is not access token
+	w = requestWithToken("GET", "/v1/me", e.PasswordChangeToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = requestWithToken("PUT", "/v1/me/password", e.PasswordChangeToken, entity.ChangePassword{Password: password, NewPassword: "newPassword1"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	c := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	w = requestWithToken("GET", "/v1/me", c.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// token is rejected after password is changed
+	w = requestWithToken("PUT", "/v1/me/password", e.PasswordChangeToken, entity.ChangePassword{Password: password, NewPassword: "newPassword2"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	login(t, r, "testuser", "newPassword1")
+}
+
+func TestLoginPasswordExpiredMFA(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	changed := time.Now().Add(-91 * 24 * time.Hour)
+	ur := &mock.UserRepository{User: &entity.User{
+		ID:                1,
+		Account:           "testuser",
+		Password:          string(cryptedPassword),
+		PasswordChangedAt: &changed,
+		IsEnable:          true,
+		IsActive:          true,
+		MFAEnabled:        true,
+		MFASecret:         testTOTPSecret,
+	}}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	d.Policy = NewPasswordPolicy(config.PasswordPolicy{MaxAge: 90 * 24 * time.Hour}, ur, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	middleware, err := NewAuthMiddleware(d).Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.POST("/v1/auth/mfa", middleware.MFAHandler)
+
+	// token for changing password is not issued until second factor is verified
+	j, _ := json.Marshal(entity.Authenticate{Account: "testuser", Password: password})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	challenge := entity.MFAChallenge{}
+	if err := json.Unmarshal(w.Body.Bytes(), &challenge); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, challenge.MFARequired)
+
+	code, _ := totpCode(testTOTPSecret, time.Now().Unix()/totpPeriod)
+	j, _ = json.Marshal(entity.AuthenticateMFA{ChallengeToken: challenge.ChallengeToken, Code: code})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/v1/auth/mfa", bytes.NewBuffer(j))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), errPasswordExpired.Error())
+
+	e := entity.PasswordChangeRequired{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, e.PasswordChangeToken)
+}
+
 func TestLoginSuccess(t *testing.T) {
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
 
 	password := "password"
 	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
-	m := NewAuthMiddleware(&mock.UserRepository{User: &entity.User{
+	ur := &mock.UserRepository{User: &entity.User{
 		Account:  "testuser",
 		Password: string(cryptedPassword),
 		IsEnable: true,
 		IsActive: true,
-	}})
+	}}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	m := NewAuthMiddleware(d)
 	middleware, err := m.Create()
 	if err != nil {
 		t.Fatal(err)
@@ -207,4 +438,791 @@ func TestLoginSuccess(t *testing.T) {
 		t.Error(err)
 	}
 	assert.NotEmpty(t, c.Token)
+
+	// password is given to be rehashed if the hash is outdated
+	assert.Equal(t, password, ur.Rehashed)
+}
+
+func TestLoginSuccessWithPrivateKey(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ks, err := NewKeySet(config.JWT{PrivateKeyFile: writePrivateKey(t, key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	d := newTestAuthDeps(t)
+	d.Keys = ks
+	d.Users = &mock.UserRepository{User: &entity.User{
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}}
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.GET("/v1/me", middleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	e := entity.Authenticate{
+		Account:  "testuser",
+		Password: password,
+	}
+	j, err := json.Marshal(e)
+	if err != nil {
+		t.Error(err)
+	}
+	body := bytes.NewBuffer(j)
+	req, _ := http.NewRequest("POST", "/v1/auth", body)
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	c := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+		t.Error(err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func login(t *testing.T, r *gin.Engine, account, password string) entity.Claim {
+	j, err := json.Marshal(entity.Authenticate{
+		Account:  account,
+		Password: password,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("login failed: %d", w.Code)
+	}
+
+	c := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+		t.Error(err)
+	}
+	return c
+}
+
+func TestLogoutRevokeToken(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: &entity.User{
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}}
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	auth := r.Group("/v1", middleware.MiddlewareFunc())
+	auth.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	auth.DELETE("/deauth", middleware.LogoutHandler)
+
+	c := login(t, r, "testuser", password)
+	other := login(t, r, "testuser", password)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/v1/deauth", nil)
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusNoContent)
+
+	// revoked token
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	// other token is still valid
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+other.Token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestTokenRefresh(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	changed := time.Now()
+	user := &entity.User{
+		ID:                1,
+		Account:           "testuser",
+		Password:          string(cryptedPassword),
+		PasswordChangedAt: &changed,
+		IsEnable:          true,
+		IsActive:          true,
+	}
+	ur := &mock.UserRepository{User: user}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	d.Policy = NewPasswordPolicy(config.PasswordPolicy{MaxAge: 90 * 24 * time.Hour}, ur, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.POST("/v1/token/refresh", middleware.TokenRefreshHandler)
+	r.GET("/v1/me", middleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	refresh := func(token string) (int, entity.Claim) {
+		j, _ := json.Marshal(entity.Refresh{RefreshToken: token})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/token/refresh", bytes.NewBuffer(j))
+		r.ServeHTTP(w, req)
+
+		c := entity.Claim{}
+		if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+			t.Error(err)
+		}
+		return w.Code, c
+	}
+
+	c := login(t, r, "testuser", password)
+	assert.NotEmpty(t, c.RefreshToken)
+
+	// rotate
+	code, rotated := refresh(c.RefreshToken)
+	assert.Equal(t, code, http.StatusOK)
+	assert.NotEmpty(t, rotated.Token)
+	assert.NotEqual(t, c.RefreshToken, rotated.RefreshToken)
+
+	// unknown token
+	code, _ = refresh("unknown")
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	me := func(token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, me(rotated.Token))
+
+	// reuse rotated token revokes the family and the session
+	code, _ = refresh(c.RefreshToken)
+	assert.Equal(t, code, http.StatusUnauthorized)
+	code, _ = refresh(rotated.RefreshToken)
+	assert.Equal(t, code, http.StatusUnauthorized)
+	assert.Equal(t, http.StatusUnauthorized, me(rotated.Token))
+
+	// other family is not affected
+	other := login(t, r, "testuser", password)
+	code, other = refresh(other.RefreshToken)
+	assert.Equal(t, code, http.StatusOK)
+
+	// refreshing is not a way around changing password
+	expired := login(t, r, "testuser", password)
+	user.IsActive = false
+	code, _ = refresh(other.RefreshToken)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	user.IsActive = true
+	changed = time.Now().Add(-91 * 24 * time.Hour)
+	code, _ = refresh(expired.RefreshToken)
+	assert.Equal(t, code, http.StatusUnauthorized)
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	ks := newTestKeySet(t)
+	sr := &mock.SessionRepository{}
+	d := newTestAuthDeps(t)
+	d.Keys = ks
+	d.Users = &mock.UserRepository{User: &entity.User{
+		ID:       1,
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}}
+	d.Sessions = sr
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.POST("/v1/token/refresh", middleware.TokenRefreshHandler)
+	auth := r.Group("/v1", middleware.MiddlewareFunc())
+	auth.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	auth.DELETE("/deauth", middleware.LogoutHandler)
+
+	request := func(method, path, token string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	claims := func(token string) gojwt.MapClaims {
+		parsed, err := gojwt.Parse(token, ks.KeyFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Claims.(gojwt.MapClaims)
+	}
+
+	c := login(t, r, "testuser", password)
+	other := login(t, r, "testuser", password)
+
+	sessions, _ := sr.FindAll(ctx, 1)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, float64(sessions[0].ID), claims(c.Token)[config.SessionKey])
+	assert.Equal(t, claims(c.Token)["jti"], sessions[0].TokenID)
+
+	// session is continued by refresh token
+	j, _ := json.Marshal(entity.Refresh{RefreshToken: c.RefreshToken})
+	w := request("POST", "/v1/token/refresh", "", j)
+	assert.Equal(t, http.StatusOK, w.Code)
+	rotated := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &rotated); err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, float64(sessions[0].ID), claims(rotated.Token)[config.SessionKey])
+	s, _ := sr.Find(ctx, sessions[0].ID)
+	assert.Equal(t, claims(rotated.Token)["jti"], s.TokenID)
+
+	// revoked session
+	assert.Nil(t, sr.Revoke(ctx, s))
+	w = request("GET", "/v1/me", rotated.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = request("GET", "/v1/me", c.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// other session is still valid
+	w = request("GET", "/v1/me", other.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// logout ends the session
+	w = request("DELETE", "/v1/deauth", other.Token, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	sessions, _ = sr.FindAll(ctx, 1)
+	assert.Empty(t, sessions)
+
+	j, _ = json.Marshal(entity.Refresh{RefreshToken: other.RefreshToken})
+	w = request("POST", "/v1/token/refresh", "", j)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRegisteredClaims(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	ks := newTestKeySet(t)
+	c := config.JWT{Issuer: "https://auth.example.com", Audience: "example"}
+	d := newTestAuthDeps(t)
+	d.Config = c
+	d.Keys = ks
+	d.Users = &mock.UserRepository{User: &entity.User{
+		ID:       10,
+		Role:     entity.RoleGeneral,
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}}
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.GET("/v1/me", middleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	claim := login(t, r, "testuser", password)
+	token, err := gojwt.Parse(claim.Token, ks.KeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(gojwt.MapClaims)
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	assert.Equal(t, "example", claims["aud"])
+	assert.Equal(t, "10", claims["sub"])
+	assert.Equal(t, "General", claims["role"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotEmpty(t, claims["iat"])
+	assert.NotEmpty(t, claims["nbf"])
+
+	get := func(claims gojwt.MapClaims) int {
+		s, _ := ks.Sign(claims)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+s)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	assert.Equal(t, http.StatusOK, get(gojwt.MapClaims{"id": 10, "exp": exp, "iss": c.Issuer, "aud": c.Audience}))
+	// issuer not matched
+	assert.Equal(t, http.StatusUnauthorized, get(gojwt.MapClaims{"id": 10, "exp": exp, "iss": "other", "aud": c.Audience}))
+	// audience not matched
+	assert.Equal(t, http.StatusUnauthorized, get(gojwt.MapClaims{"id": 10, "exp": exp, "iss": c.Issuer, "aud": "other"}))
+	// invalid type of claims
+	assert.Equal(t, http.StatusUnauthorized, get(gojwt.MapClaims{"id": "10", "exp": exp, "iss": c.Issuer, "aud": c.Audience}))
+	assert.Equal(t, http.StatusUnauthorized, get(gojwt.MapClaims{"id": 10, "sub": 10, "exp": exp, "iss": c.Issuer, "aud": c.Audience}))
+	// not yet valid
+	assert.Equal(t, http.StatusUnauthorized, get(gojwt.MapClaims{"id": 10, "exp": exp, "nbf": exp, "iss": c.Issuer, "aud": c.Audience}))
+}
+
+func TestRequireRole(t *testing.T) {
+	m := NewAuthMiddleware(newTestAuthDeps(t))
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		user *entity.User
+		code int
+	}{
+		{nil, http.StatusForbidden},
+		{&entity.User{Role: entity.RoleGeneral}, http.StatusForbidden},
+		{&entity.User{Role: entity.RoleAdministrator}, http.StatusOK},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		if c.user != nil {
+			r.Use(setIdentity(c.user))
+		}
+		r.GET("/v1/admin", middleware.RequireRole(entity.RoleAdministrator), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		req, _ := http.NewRequest("GET", "/v1/admin", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, c.code, w.Code)
+		if c.code == http.StatusForbidden {
+			e := entity.Error{}
+			if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+				t.Error(err)
+			}
+			assert.Equal(t, http.StatusForbidden, e.Code)
+		}
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", Role: entity.RoleGeneral, IsEnable: true}
+	kr := &mock.APIKeyRepository{}
+	key, _ := kr.Create(ctx, &entity.APIKey{UserID: 1, Name: "script", Scope: "profile"})
+	expiredAt := time.Now().Add(-time.Minute)
+	expired, _ := kr.Create(ctx, &entity.APIKey{UserID: 1, Name: "expired", ExpiredAt: &expiredAt})
+
+	d := newTestAuthDeps(t)
+	d.Users = &mock.UserRepository{User: user}
+	d.APIKeys = kr
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.GET("/v1/me", middleware.MiddlewareFunc(), middleware.RequireScope(entity.ScopeProfile), func(c *gin.Context) {
+		identity, _ := c.Get(config.IdentityKey)
+		c.JSON(http.StatusOK, gin.H{
+			"account": identity.(*entity.User).Account,
+			"scope":   jwt.ExtractClaims(c)["scope"],
+		})
+	})
+	r.GET("/v1/admin/users", middleware.MiddlewareFunc(), middleware.RequireScope(entity.ScopeAdmin), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	r.GET("/v1/me/api-keys", middleware.MiddlewareFunc(), middleware.RequireSession(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	request := func(key string, path ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		url := "/v1/me"
+		if len(path) > 0 {
+			url = path[0]
+		}
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	{
+		w := request(key)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"account":"testuser","scope":"profile"}`, w.Body.String())
+
+		k, _ := kr.FindByKey(ctx, key)
+		assert.NotNil(t, k.LastUsedAt)
+	}
+	{
+		// scope not granted to the key
+		w := request(key, "/v1/admin/users")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), errInsufficientScope.Error())
+
+		// credentials are not managed with the key
+		w = request(key, "/v1/me/api-keys")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), errSessionRequired.Error())
+	}
+	{
+		// token issued by login is not limited by scope
+		token, _, _ := middleware.(*jwtMiddleware).TokenGenerator(user)
+		for _, path := range []string{"/v1/me", "/v1/admin/users", "/v1/me/api-keys"} {
+			w := request(token, path)
+			assert.Equal(t, http.StatusOK, w.Code, path)
+		}
+	}
+	for _, key := range []string{expired, config.APIKeyPrefix + "unknown"} {
+		w := request(key)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), errInvalidAPIKey.Error())
+	}
+	{
+		// key issued before changing password
+		user.TokenVersion++
+		w := request(key)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), errInvalidAPIKey.Error())
+		user.TokenVersion--
+	}
+	{
+		// disabled user
+		user.IsEnable = false
+		w := request(key)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	ur := &mock.UserRepository{User: &entity.User{
+		ID:       1,
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}, IsMatchPassword: true}
+	d := newTestAuthDeps(t)
+	d.Users = ur
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.POST("/v1/token/refresh", middleware.TokenRefreshHandler)
+	auth := r.Group("/v1", middleware.MiddlewareFunc())
+	auth.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	auth.PUT("/me/password", middleware.ChangePasswordHandler)
+
+	request := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(j))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	c := login(t, r, "testuser", password)
+	other := login(t, r, "testuser", password)
+
+	// invalid password
+	w := request("PUT", "/v1/me/password", c.Token, entity.ChangePassword{Password: password, NewPassword: "short"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	// password violating the policy
+	w = request("PUT", "/v1/me/password", c.Token, entity.ChangePassword{Password: password, NewPassword: "TestUser001"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Contains(t, w.Body.String(), "account or name")
+
+	// same password
+	w = request("PUT", "/v1/me/password", c.Token, entity.ChangePassword{Password: password, NewPassword: password})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	// current password not matched
+	ur.IsMatchPassword = false
+	w = request("PUT", "/v1/me/password", c.Token, entity.ChangePassword{Password: "Invalid001", NewPassword: "NewPassword001"})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	ur.IsMatchPassword = true
+
+	w = request("PUT", "/v1/me/password", c.Token, entity.ChangePassword{Password: password, NewPassword: "NewPassword001"})
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	changed := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &changed); err != nil {
+		t.Error(err)
+	}
+	assert.NotEmpty(t, changed.Token)
+	assert.NotEmpty(t, changed.RefreshToken)
+
+	// other sessions are revoked
+	for _, token := range []string{c.Token, other.Token} {
+		w = request("GET", "/v1/me", token, nil)
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	}
+	for _, token := range []string{c.RefreshToken, other.RefreshToken} {
+		w = request("POST", "/v1/token/refresh", "", entity.Refresh{RefreshToken: token})
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	}
+
+	// current session continues with new token
+	w = request("GET", "/v1/me", changed.Token, nil)
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = request("POST", "/v1/token/refresh", "", entity.Refresh{RefreshToken: changed.RefreshToken})
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestLoginLockout(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	user := &entity.User{
+		ID:       1,
+		Account:  "testuser",
+		Password: string(cryptedPassword),
+		IsEnable: true,
+		IsActive: true,
+	}
+	d := newTestAuthDeps(t)
+	d.Lockout = config.Lockout{Threshold: 2, Duration: time.Minute}
+	d.Users = &mock.UserRepository{User: user}
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+
+	authenticate := func(password string) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(entity.Authenticate{Account: "testuser", Password: password})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// failure is reset after successful login
+	assert.Equal(t, authenticate("Invalid001").Code, http.StatusUnauthorized)
+	assert.Equal(t, uint(1), user.FailedLogins)
+	assert.Equal(t, authenticate(password).Code, http.StatusOK)
+	assert.Equal(t, uint(0), user.FailedLogins)
+
+	w := authenticate("Invalid001")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.NotContains(t, w.Body.String(), errAccountLocked.Error())
+
+	// locked when reached to threshold
+	w = authenticate("Invalid001")
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errAccountLocked.Error())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *user.LockedUntil, time.Second)
+
+	// correct password is also rejected while locking
+	w = authenticate(password)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errAccountLocked.Error())
+
+	// unlocked after cooldown, and locking duration is doubled at next failure
+	past := time.Now().Add(-time.Second)
+	user.LockedUntil = &past
+	w = authenticate("Invalid001")
+	assert.Contains(t, w.Body.String(), errAccountLocked.Error())
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), *user.LockedUntil, time.Second)
+
+	// the duration is limited
+	user.LockedUntil = &past
+	user.FailedLogins = 100
+	authenticate("Invalid001")
+	assert.WithinDuration(t, time.Now().Add(maxLockTimeout), *user.LockedUntil, time.Second)
+
+	user.LockedUntil = &past
+	assert.Equal(t, authenticate(password).Code, http.StatusOK)
+	assert.Nil(t, user.LockedUntil)
+}
+
+func TestLoginMFA(t *testing.T) {
+	ctx := context.Background()
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	password := "password"
+	cryptedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	user := &entity.User{
+		ID:         1,
+		Account:    "testuser",
+		Password:   string(cryptedPassword),
+		IsEnable:   true,
+		IsActive:   true,
+		MFASecret:  testTOTPSecret,
+		MFAEnabled: true,
+	}
+	rc := &mock.RecoveryCodeRepository{}
+	codes, _ := rc.Create(ctx, user.ID, 2)
+	d := newTestAuthDeps(t)
+	d.Lockout = config.Lockout{Threshold: 3, Duration: time.Minute}
+	d.Users = &mock.UserRepository{User: user}
+	d.Recovery = rc
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fixed clock for generating code
+	now := time.Now()
+	middleware.(*jwtMiddleware).TimeFunc = func() time.Time { return now }
+	code, _ := totpCode(testTOTPSecret, now.Unix()/totpPeriod)
+
+	r.POST("/v1/auth", middleware.LoginHandler)
+	r.POST("/v1/auth/mfa", middleware.MFAHandler)
+	auth := r.Group("/v1", middleware.MiddlewareFunc())
+	auth.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	challenge := func() string {
+		j, _ := json.Marshal(entity.Authenticate{Account: "testuser", Password: password})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+
+		e := entity.MFAChallenge{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.True(t, e.MFARequired)
+		return e.ChallengeToken
+	}
+	get := func(token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token := challenge()
+
+	// challenge token is not access token, it has neither identity nor audience of access token
+	assert.Equal(t, get(token), http.StatusUnauthorized)
+	parsed, err := gojwt.Parse(token, middleware.(*jwtMiddleware).keys.KeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := parsed.Claims.(gojwt.MapClaims)
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, mfaChallengeAudience, claims["aud"])
+	assert.NotContains(t, claims, config.IdentityKey)
+	assert.NotContains(t, claims, config.RoleKey)
+
+	w := postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: "invalid", Code: code})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	// failure is counted as failed login
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, Code: "000000"})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Contains(t, w.Body.String(), errInvalidMFACode.Error())
+	assert.Equal(t, uint(1), user.FailedLogins)
+
+	// failure is not reset by password only
+	token = challenge()
+	assert.Equal(t, uint(1), user.FailedLogins)
+
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, Code: code})
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, uint(0), user.FailedLogins)
+
+	c := entity.Claim{}
+	if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+		t.Error(err)
+	}
+	assert.NotEmpty(t, c.RefreshToken)
+	assert.Equal(t, get(c.Token), http.StatusOK)
+
+	// challenge token is used only once
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, RecoveryCode: codes[0]})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	// the same code is not accepted again
+	token = challenge()
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, Code: code})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	// recovery code is used only once
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, RecoveryCode: codes[0]})
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	token = challenge()
+	w = postJSON(r, "/v1/auth/mfa", entity.AuthenticateMFA{ChallengeToken: token, RecoveryCode: codes[0]})
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
 }
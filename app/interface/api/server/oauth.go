@@ -0,0 +1,287 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	clientTimeout              time.Duration = time.Hour
+	grantTypeClientCredentials               = "client_credentials"
+	grantTypeAuthorizationCode               = "authorization_code"
+)
+
+type oauthHandler struct {
+	*jwtMiddleware
+	clients repository.Client
+	codes   repository.AuthorizationCode
+}
+
+// NewOAuthHandler is create action handler for OAuth authorization server,
+// tokens are issued in the same manner as the middleware created by NewAuthMiddleware with the dependencies.
+func NewOAuthHandler(d AuthDeps, cr repository.Client, ar repository.AuthorizationCode) (handler.OAuth, error) {
+	mw, err := newJWTAuth(d).create()
+	if err != nil {
+		return nil, err
+	}
+	return &oauthHandler{
+		jwtMiddleware: mw,
+		clients:       cr,
+		codes:         ar,
+	}, nil
+}
+
+// Error codes of authorization and token endpoint (RFC 6749 section 4.1.2.1 and 5.2)
+const (
+	oauthInvalidRequest          = "invalid_request"
+	oauthInvalidClient           = "invalid_client"
+	oauthInvalidGrant            = "invalid_grant"
+	oauthInvalidScope            = "invalid_scope"
+	oauthUnauthorizedClient      = "unauthorized_client"
+	oauthUnsupportedGrantType    = "unsupported_grant_type"
+	oauthUnsupportedResponseType = "unsupported_response_type"
+	oauthAccessDenied            = "access_denied"
+	// Error code of protected resource (RFC 6750 section 3.1)
+	oauthInsufficientScope = "insufficient_scope"
+)
+
+// Token is issue token for OAuth client (RFC 6749).
+// Client is authenticated with HTTP Basic authentication or client_id and client_secret parameters,
+// public client is identified only with client_id and it is allowed only authorization code with PKCE.
+// @Summary Issue token for OAuth client
+// @Tags OAuth
+// @Accept  x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "grant type" Enums(client_credentials, authorization_code)
+// @Param scope formData string false "space-delimited scope for client_credentials, all scopes of client if empty"
+// @Param code formData string false "authorization code for authorization_code"
+// @Param redirect_uri formData string false "redirect URI for authorization_code, if it was included in authorization request"
+// @Param code_verifier formData string false "PKCE code verifier for authorization_code"
+// @Param client_id formData string false "client ID, if not using basic authentication"
+// @Param client_secret formData string false "client secret, if not using basic authentication"
+// @Success 200 {object} entity.OAuthToken
+// @Failure 400 {object} entity.OAuthError
+// @Failure 401 {object} entity.OAuthError
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /oauth/token [post]
+func (h *oauthHandler) Token(c *gin.Context) {
+	// Response including token must not be cached
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var p entity.Token
+	if err := c.ShouldBindWith(&p, binding.Form); err != nil {
+		oauthError(c, http.StatusBadRequest, oauthInvalidRequest, "grant_type is required")
+		return
+	}
+
+	client, ok := authenticateClient(c, h.clients, p.ClientID, p.ClientSecret)
+	if !ok {
+		return
+	}
+
+	switch p.GrantType {
+	case grantTypeClientCredentials:
+		if client.Public {
+			oauthError(c, http.StatusBadRequest, oauthUnauthorizedClient, "public client is not allowed client_credentials")
+			return
+		}
+		h.clientCredentials(c, client, p)
+	case grantTypeAuthorizationCode:
+		h.authorizationCode(c, client, p)
+	default:
+		oauthError(c, http.StatusBadRequest, oauthUnsupportedGrantType, "")
+	}
+}
+
+// Issue token whose subject is the client
+func (h *oauthHandler) clientCredentials(c *gin.Context, client *entity.Client, p entity.Token) {
+	scope, ok := grantScope(client, p.Scope)
+	if !ok {
+		oauthError(c, http.StatusBadRequest, oauthInvalidScope, "scope is not allowed for the client")
+		return
+	}
+
+	claims := gojwt.MapClaims{
+		"sub":       client.ClientID,
+		"client_id": client.ClientID,
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	token, _, err := h.sign(claims, clientTimeout)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity.OAuthToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(clientTimeout.Seconds()),
+		Scope:       scope,
+	})
+}
+
+// Exchange authorization code for token of the user who approved, code verifier of PKCE is required.
+// ID token is also issued if openid scope is granted.
+func (h *oauthHandler) authorizationCode(c *gin.Context, client *entity.Client, p entity.Token) {
+	if p.Code == "" || p.CodeVerifier == "" {
+		oauthError(c, http.StatusBadRequest, oauthInvalidRequest, "code and code_verifier are required")
+		return
+	}
+
+	ac, err := h.codes.FindByCode(c.Request.Context(), p.Code)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if ac == nil || ac.ClientID != client.ClientID || ac.RedirectURI != p.RedirectURI ||
+		!ac.ExpiredAt.After(h.TimeFunc()) || !verifyCodeChallenge(ac.CodeChallenge, p.CodeVerifier) {
+		oauthError(c, http.StatusBadRequest, oauthInvalidGrant, "authorization code is invalid")
+		return
+	}
+
+	if used, err := h.codes.Use(c.Request.Context(), ac); err != nil {
+		errorInternalServerError(c, err)
+		return
+	} else if !used {
+		oauthError(c, http.StatusBadRequest, oauthInvalidGrant, "authorization code is invalid")
+		return
+	}
+
+	user, err := h.repo.Find(c.Request.Context(), ac.UserID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || !user.Valid() {
+		oauthError(c, http.StatusBadRequest, oauthInvalidGrant, "user is invalid")
+		return
+	}
+
+	token, _, refreshToken, err := h.startSession(c, user, client.ClientID, ac.Scope)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	// ID token of OpenID Connect is issued only if openid scope is granted
+	var idToken string
+	if hasScope(ac.Scope, scopeOpenID) {
+		if idToken, err = h.idToken(user, ac); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, entity.OAuthToken{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.Timeout.Seconds()),
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        ac.Scope,
+	})
+}
+
+// Authenticate client with credentials of basic authentication or request parameters,
+// return false if response is already written.
+func authenticateClient(c *gin.Context, clients repository.Client, clientID, clientSecret string) (*entity.Client, bool) {
+	id, secret, basic := c.Request.BasicAuth()
+	if basic {
+		if clientID != "" || clientSecret != "" {
+			oauthError(c, http.StatusBadRequest, oauthInvalidRequest, "multiple client authentication methods are used")
+			return nil, false
+		}
+		// Credentials are encoded with form encoding before basic authentication (RFC 6749 section 2.3.1)
+		var err1, err2 error
+		id, err1 = url.QueryUnescape(id)
+		secret, err2 = url.QueryUnescape(secret)
+		if err1 != nil || err2 != nil {
+			oauthError(c, http.StatusBadRequest, oauthInvalidRequest, "client credentials are malformed")
+			return nil, false
+		}
+	} else {
+		id, secret = clientID, clientSecret
+	}
+
+	invalid := func() {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`"`)
+		}
+		oauthError(c, http.StatusUnauthorized, oauthInvalidClient, "client authentication failed")
+	}
+	if id == "" {
+		invalid()
+		return nil, false
+	}
+
+	client, err := clients.FindByClientID(c.Request.Context(), id)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return nil, false
+	}
+	if client == nil || !client.IsEnable {
+		invalid()
+		return nil, false
+	}
+	if client.Public {
+		if secret != "" {
+			invalid()
+			return nil, false
+		}
+		return client, true
+	}
+	if err := clients.MatchSecret(c.Request.Context(), client.SecretHash, secret); err != nil {
+		log.Error().Err(err).Msg("")
+		invalid()
+		return nil, false
+	}
+	return client, true
+}
+
+// Return granted scope, all scopes of client are granted if not requested.
+// Return false if requested scope includes scope which is not allowed for client.
+func grantScope(client *entity.Client, requested string) (string, bool) {
+	allowed := client.Scopes()
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), true
+	}
+
+	granted := []string{}
+	for _, s := range strings.Fields(requested) {
+		found := false
+		for _, a := range allowed {
+			if s == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+		granted = append(granted, s)
+	}
+	return strings.Join(granted, " "), true
+}
+
+// Return error response of OAuth (RFC 6749 section 5.2).
+func oauthError(c *gin.Context, code int, err, description string) {
+	c.AbortWithStatusJSON(code, entity.OAuthError{
+		Error:            err,
+		ErrorDescription: description,
+	})
+}
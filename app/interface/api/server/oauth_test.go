@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Create router of token endpoint with the clients
+func newTestOAuthRouter(t *testing.T, ks *KeySet, cr *mock.ClientRepository) *gin.Engine {
+	d := newTestAuthDeps(t)
+	d.Config = config.JWT{Issuer: "https://auth.example.com"}
+	d.Keys = ks
+	m := NewAuthMiddleware(d)
+	middleware, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oh, err := NewOAuthHandler(d, cr, &mock.AuthorizationCodeRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/oauth/token", oh.Token)
+	r.GET("/v1/me", middleware.MiddlewareFunc(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return r
+}
+
+func requestToken(r *gin.Engine, form url.Values, basic ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(basic) == 2 {
+		req.SetBasicAuth(basic[0], basic[1])
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	ks := newTestKeySet(t)
+	cr := &mock.ClientRepository{}
+	client := entity.Client{Name: "batch", Scope: "read write", IsEnable: true}
+	secret, _ := cr.Create(ctx, &client)
+	r := newTestOAuthRouter(t, ks, cr)
+
+	{
+		w := requestToken(r, url.Values{"grant_type": {"client_credentials"}}, client.ClientID, secret)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		e := entity.OAuthToken{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, "Bearer", e.TokenType)
+		assert.Equal(t, int64(clientTimeout.Seconds()), e.ExpiresIn)
+		assert.Equal(t, "read write", e.Scope)
+
+		token, err := gojwt.Parse(e.AccessToken, ks.KeyFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims := token.Claims.(gojwt.MapClaims)
+		assert.Equal(t, client.ClientID, claims["sub"])
+		assert.Equal(t, client.ClientID, claims["client_id"])
+		assert.Equal(t, "read write", claims["scope"])
+		assert.Equal(t, "https://auth.example.com", claims["iss"])
+
+		// token of client is not accepted as token of user
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+e.AccessToken)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	{
+		// credentials in parameters, with narrowed scope
+		w := requestToken(r, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {client.ClientID},
+			"client_secret": {secret},
+			"scope":         {"read"},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"scope":"read"`)
+	}
+	{
+		w := requestToken(r, url.Values{"grant_type": {"client_credentials"}, "scope": {"read admin"}}, client.ClientID, secret)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), oauthInvalidScope)
+	}
+	{
+		w := requestToken(r, url.Values{"grant_type": {"password"}}, client.ClientID, secret)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), oauthUnsupportedGrantType)
+	}
+	{
+		w := requestToken(r, url.Values{}, client.ClientID, secret)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), oauthInvalidRequest)
+	}
+	{
+		// multiple authentication methods
+		w := requestToken(r, url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ClientID}}, client.ClientID, secret)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), oauthInvalidRequest)
+	}
+}
+
+func TestClientCredentialsInvalidClient(t *testing.T) {
+	ctx := context.Background()
+	cr := &mock.ClientRepository{}
+	client := entity.Client{Name: "batch", IsEnable: true}
+	secret, _ := cr.Create(ctx, &client)
+	disabled := entity.Client{Name: "disabled", IsEnable: false}
+	disabledSecret, _ := cr.Create(ctx, &disabled)
+	r := newTestOAuthRouter(t, newTestKeySet(t), cr)
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	w := requestToken(r, form, client.ClientID, "invalid")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), oauthInvalidClient)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	w = requestToken(r, form, "unknown", secret)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = requestToken(r, form, disabled.ClientID, disabledSecret)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// no client authentication
+	w = requestToken(r, form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+
+	// client without scope is issued token without scope
+	w = requestToken(r, form, client.ClientID, secret)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "scope")
+}
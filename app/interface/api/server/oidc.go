@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+// Scopes of OpenID Connect, client must be registered with the scopes to request them
+const (
+	scopeOpenID  = "openid"
+	scopeProfile = entity.ScopeProfile
+	scopeEmail   = "email"
+)
+
+// Maximum length of nonce, it is stored with authorization code
+const maxNonceLength = 255
+
+type oidcHandler struct {
+	config config.JWT
+	keys   *KeySet
+}
+
+// NewOIDCHandler is create action handler for OpenID Provider
+func NewOIDCHandler(c config.JWT, ks *KeySet) handler.OIDC {
+	return &oidcHandler{
+		config: c,
+		keys:   ks,
+	}
+}
+
+// Configuration is get metadata of OpenID Provider (OpenID Connect Discovery 1.0)
+// @Summary Return metadata of OpenID Provider
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} entity.OpenIDConfiguration
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /.well-known/openid-configuration [get]
+func (h *oidcHandler) Configuration(c *gin.Context) {
+	base := strings.TrimSuffix(h.config.Issuer, "/")
+	c.JSON(http.StatusOK, entity.OpenIDConfiguration{
+		Issuer:                            h.config.Issuer,
+		AuthorizationEndpoint:             base + "/oauth/authorize",
+		TokenEndpoint:                     base + "/oauth/token",
+		UserInfoEndpoint:                  base + "/oauth/userinfo",
+		JWKSURI:                           base + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{scopeOpenID, scopeProfile, scopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.keys.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "name", "preferred_username", "gender", "birthdate", "email"},
+	})
+}
+
+// UserInfo is get standard claims of the user authorized with openid scope
+// @Summary Return standard claims of OpenID Connect for authenticated user
+// @Tags OAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} entity.UserInfo
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.OAuthError
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /oauth/userinfo [get]
+// @Router /oauth/userinfo [post]
+func (h *oidcHandler) UserInfo(c *gin.Context) {
+	scope, _ := jwt.ExtractClaims(c)["scope"].(string)
+	if !hasScope(scope, scopeOpenID) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		oauthError(c, http.StatusForbidden, oauthInsufficientScope, "token is not granted openid scope")
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	c.JSON(http.StatusOK, user.UserInfo(strings.Fields(scope)))
+}
+
+// Issue ID token for the client, claims of the user are included for granted scopes.
+// The token has no identity claim, so it is not accepted as access token.
+func (h *oauthHandler) idToken(user *entity.User, ac *entity.AuthorizationCode) (string, error) {
+	info := user.UserInfo(strings.Fields(ac.Scope))
+	claims := gojwt.MapClaims{
+		"iss": h.config.Issuer,
+		"sub": info.Sub,
+		"aud": ac.ClientID,
+	}
+	for key, value := range map[string]string{
+		"name":               info.Name,
+		"preferred_username": info.PreferredUsername,
+		"gender":             info.Gender,
+		"birthdate":          info.Birthdate,
+		"email":              info.Email,
+		"nonce":              ac.Nonce,
+	} {
+		if value != "" {
+			claims[key] = value
+		}
+	}
+
+	token, _, err := h.sign(claims, h.Timeout)
+	return token, err
+}
+
+// Check space-delimited scope includes the scope
+func hasScope(scope, s string) bool {
+	for _, v := range strings.Fields(scope) {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenIDConfiguration(t *testing.T) {
+	for issuer, expected := range map[string]string{
+		"https://auth.example.com":  "https://auth.example.com",
+		"https://auth.example.com/": "https://auth.example.com/",
+	} {
+		h := NewOIDCHandler(config.JWT{Issuer: issuer}, newTestKeySet(t))
+
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.GET("/.well-known/openid-configuration", h.Configuration)
+
+		// Host of request is not used as issuer, it is able to be forged by client
+		req, _ := http.NewRequest("GET", "http://attacker.example.com/.well-known/openid-configuration", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		e := entity.OpenIDConfiguration{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, expected, e.Issuer)
+		assert.Equal(t, strings.TrimSuffix(expected, "/")+"/oauth/token", e.TokenEndpoint)
+		assert.Equal(t, strings.TrimSuffix(expected, "/")+"/.well-known/jwks.json", e.JWKSURI)
+		assert.Equal(t, []string{"HS256"}, e.IDTokenSigningAlgValuesSupported)
+		assert.Equal(t, []string{"S256"}, e.CodeChallengeMethodsSupported)
+	}
+}
+
+func TestIDToken(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+	birthday, _ := time.Parse("2006-01-02", "2000-01-31")
+	s.user.Name = "Test User"
+	s.user.Gender = entity.GenderMale
+	s.user.MailAddress = "test@example.com"
+	s.user.Birthday = entity.Date{Time: birthday}
+
+	params := s.params()
+	params.Set("scope", "openid profile")
+	params.Set("nonce", "n-0S6_WzA2Mj")
+	code := s.approveWith(t, params)
+
+	w := requestToken(s.router, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {s.client.ClientID},
+		"code":          {code},
+		"code_verifier": {testCodeVerifier},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	e := entity.OAuthToken{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.NotEmpty(t, e.IDToken)
+
+	token, err := gojwt.Parse(e.IDToken, s.keys.KeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(gojwt.MapClaims)
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, s.client.ClientID, claims["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.Equal(t, "Test User", claims["name"])
+	assert.Equal(t, "male", claims["gender"])
+	assert.Equal(t, "2000-01-31", claims["birthdate"])
+	assert.NotContains(t, claims, "email")
+	assert.NotContains(t, claims, "id")
+
+	// ID token is not accepted as access token
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/oauth/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+e.IDToken)
+	s.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// ID token is not issued without openid scope
+	code = s.approve(t)
+	w = requestToken(s.router, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {s.client.ClientID},
+		"code":          {code},
+		"code_verifier": {testCodeVerifier},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "id_token")
+}
+
+func TestAuthorizeNonceTooLong(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+
+	params := s.params()
+	params.Set("nonce", strings.Repeat("a", maxNonceLength+1))
+	w := s.authorize(params)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "error="+oauthInvalidRequest)
+}
+
+func TestUserInfo(t *testing.T) {
+	s := newTestAuthorizeServer(t)
+	s.user.Name = "Test User"
+	s.user.MailAddress = "test@example.com"
+
+	userInfo := func(scope string) *httptest.ResponseRecorder {
+		params := s.params()
+		params.Set("scope", scope)
+		code := s.approveWith(t, params)
+
+		w := requestToken(s.router, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {s.client.ClientID},
+			"code":          {code},
+			"code_verifier": {testCodeVerifier},
+		})
+		e := entity.OAuthToken{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/oauth/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+e.AccessToken)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	{
+		w := userInfo("openid email")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		e := entity.UserInfo{}
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, entity.UserInfo{Sub: "1", Email: "test@example.com"}, e)
+	}
+	{
+		w := userInfo("read")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), oauthInsufficientScope)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	resetTimeout time.Duration = time.Hour
+)
+
+type passwordHandler struct {
+	repo     repository.User
+	resets   repository.PasswordReset
+	refresh  repository.RefreshToken
+	sessions repository.Session
+	audit    repository.AuditLog
+	policy   *PasswordPolicy
+	mailer   service.Mailer
+}
+
+// NewPasswordHandler is create action handler for resetting password
+func NewPasswordHandler(ur repository.User, pr repository.PasswordReset, tr repository.RefreshToken, sr repository.Session, al repository.AuditLog, pp *PasswordPolicy, m service.Mailer) handler.Password {
+	return &passwordHandler{
+		repo:     ur,
+		resets:   pr,
+		refresh:  tr,
+		sessions: sr,
+		audit:    al,
+		policy:   pp,
+		mailer:   m,
+	}
+}
+
+// Forgot is send password reset token to verified mail address of user.
+// The response is the same whether user exists or not, so that account can not be guessed.
+// @Summary Send password reset token to user
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.ForgotPassword true "request data"
+// @Success 202
+// @Failure 400 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/password/forgot [post]
+func (h *passwordHandler) Forgot(c *gin.Context) {
+	var p entity.ForgotPassword
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	users, err := findRecipients(c.Request.Context(), h.repo, p.Account, p.MailAddress)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	for i := range users {
+		u := &users[i]
+		// Token is not sent to address not confirmed to be owned by the user, the response is the same
+		if !u.MailVerified() {
+			continue
+		}
+
+		token, err := h.resets.Create(c.Request.Context(), &entity.PasswordReset{
+			UserID:    u.ID,
+			ExpiredAt: time.Now().Add(resetTimeout),
+		})
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+
+		// Failure of delivery is not returned, the same as the user does not exist
+		if err := h.mailer.Send(resetMail(u, token)); err != nil {
+			log.Error().Err(err).Msg("")
+		}
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// Find users by account or mail address, mail address is also matched if both are given
+func findRecipients(ctx context.Context, ur repository.User, account, mailAddress string) ([]entity.User, error) {
+	if account == "" {
+		return ur.FindByMailAddress(ctx, mailAddress)
+	}
+
+	user, err := ur.FindByAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || (mailAddress != "" && mailAddress != user.MailAddress) {
+		return nil, nil
+	}
+	return []entity.User{*user}, nil
+}
+
+// Reset is update password with password reset token
+// @Summary Reset password with password reset token
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.ResetPassword true "request data"
+// @Success 200
+// @Failure 400 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/password/reset [post]
+func (h *passwordHandler) Reset(c *gin.Context) {
+	var p entity.ResetPassword
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	pr, err := h.resets.FindByToken(c.Request.Context(), p.Token)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if pr == nil || pr.UsedAt != nil || !pr.ExpiredAt.After(time.Now()) {
+		errorBadRequest(c, errInvalidResetToken)
+		return
+	}
+
+	user, err := h.repo.Find(c.Request.Context(), pr.UserID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || !user.Valid() {
+		errorBadRequest(c, errInvalidResetToken)
+		return
+	}
+
+	// Token is not used up by password violating the policy
+	if !validatePassword(c, h.policy, user, p.NewPassword) {
+		return
+	}
+
+	if used, err := h.resets.Use(c.Request.Context(), pr); err != nil {
+		errorInternalServerError(c, err)
+		return
+	} else if !used {
+		errorBadRequest(c, errInvalidResetToken)
+		return
+	}
+
+	// Sessions before reset may be used by someone who knows the forgotten password
+	user.TokenVersion++
+	if err := h.repo.UpdatePassword(c.Request.Context(), user, p.NewPassword, h.policy.Histories()); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.refresh.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := h.sessions.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditPasswordChange, nil, user, "reset"))
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// Create mail for notifying password reset token
+func resetMail(u *entity.User, token string) entity.Mail {
+	return entity.Mail{
+		To:      u.MailAddress,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello %s,\r\n\r\nUse the following token to reset the password of account \"%s\".\r\nThe token expires in %d minutes.\r\n\r\n%s\r\n\r\nIf you did not request it, please ignore this mail.\r\n",
+			u.Name, u.Account, int(resetTimeout.Minutes()), token,
+		),
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// PasswordChangeFunc is verify token only for changing password and set the user, it is issued at login of the user who must change password.
+// Other tokens and API key are verified same as MiddlewareFunc.
+func (mw *jwtMiddleware) PasswordChangeFunc() gin.HandlerFunc {
+	authenticate := mw.MiddlewareFunc()
+	return func(c *gin.Context) {
+		claims, err := mw.GetClaimsFromJWT(c)
+		if err != nil || claims[tokenTypeKey] != tokenTypePasswordChange {
+			authenticate(c)
+			return
+		}
+
+		id, err := mw.verifyChallenge(claims, tokenTypePasswordChange, passwordChangeAudience)
+		if err != nil {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+			return
+		}
+		user, err := mw.repo.Find(c.Request.Context(), id)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if user == nil || !user.Valid() {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errInvalidAccount, c))
+			return
+		}
+		// Token is rejected after password is changed, because token version is incremented
+		if revoked, err := mw.isRevoked(c.Request.Context(), claims, user); err != nil {
+			errorInternalServerError(c, err)
+			return
+		} else if revoked {
+			mw.unauthorized(c, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(errRevokedToken, c))
+			return
+		}
+
+		c.Set("JWT_PAYLOAD", claims)
+		c.Set(mw.IdentityKey, user)
+		c.Next()
+	}
+}
+
+// Respond the reason why password must be changed with token only for changing password
+func (mw *jwtMiddleware) passwordChangeResponse(c *gin.Context, user *entity.User, reason error) {
+	claims := gojwt.MapClaims{
+		"sub":                  strconv.FormatUint(uint64(user.ID), 10),
+		"aud":                  passwordChangeAudience,
+		tokenTypeKey:           tokenTypePasswordChange,
+		config.TokenVersionKey: user.TokenVersion,
+	}
+	token, expire, err := mw.sign(claims, passwordChangeTimeout)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Header("WWW-Authenticate", "JWT realm="+mw.Realm)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, entity.PasswordChangeRequired{
+		Error: entity.Error{
+			Code:    http.StatusUnauthorized,
+			Message: reason.Error(),
+		},
+		PasswordChangeToken: token,
+		Expire:              expire.Format(time.RFC3339),
+	})
+}
+
+// ChangePasswordHandler is change password of the authenticated user.
+// New password must satisfy the password policy.
+// Other sessions of the user are revoked, so new token is issued for the current session.
+// @Summary Change password of authenticated user
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Accept  json
+// @Produce json
+// @Param data body entity.ChangePassword true "request data"
+// @Success 200 {object} entity.Claim
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/password [put]
+func (mw *jwtMiddleware) ChangePasswordHandler(c *gin.Context) {
+	var p entity.ChangePassword
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	// Deny change to same password
+	if p.Password == p.NewPassword {
+		errorBadRequest(c, errSamePassword)
+		return
+	}
+
+	identity, _ := c.Get(mw.IdentityKey)
+	user, ok := identity.(*entity.User)
+	if !ok {
+		errorUnauthorized(c, errUnauthorized)
+		return
+	}
+
+	if err := mw.repo.MatchPassword(c.Request.Context(), user.Password, p.Password); err != nil {
+		errorUnauthorized(c, errUnauthorized)
+		return
+	}
+
+	if !validatePassword(c, mw.policy, user, p.NewPassword) {
+		return
+	}
+
+	// Tokens issued with previous version are rejected
+	user.TokenVersion++
+	if err := mw.repo.UpdatePassword(c.Request.Context(), user, p.NewPassword, mw.policy.Histories()); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := mw.refresh.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if err := mw.sessions.RevokeUser(c.Request.Context(), user.ID); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	recordAudit(mw.audit, c, auditEvent(entity.AuditPasswordChange, user, user, ""))
+
+	token, expire, refreshToken, err := mw.startSession(c, user, "", "")
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	loginResponse(c, http.StatusOK, token, expire, refreshToken)
+}
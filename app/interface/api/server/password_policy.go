@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+)
+
+// Account or word of name shorter than it is not checked in password
+const minUserInfoLength = 3
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// passwordPolicyErrors is violations of rules of password policy, the messages are returned to user
+type passwordPolicyErrors []string
+
+func (e passwordPolicyErrors) Error() string {
+	return strings.Join(e, ", ")
+}
+
+// PasswordPolicy is rules of password set by user
+type PasswordPolicy struct {
+	config    config.PasswordPolicy
+	blocklist map[string]bool
+	users     repository.User
+	history   repository.PasswordHistory
+	hasher    service.PasswordHasher
+}
+
+// NewPasswordPolicy is create password policy, previous passwords are verified with the user repository.
+// Password longer than the hasher is able to hash is rejected regardless of the policy.
+func NewPasswordPolicy(c config.PasswordPolicy, ur repository.User, hr repository.PasswordHistory, h service.PasswordHasher) *PasswordPolicy {
+	blocklist := map[string]bool{}
+	s := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for s.Scan() {
+		if v := strings.TrimSpace(s.Text()); v != "" && !strings.HasPrefix(v, "#") {
+			blocklist[strings.ToLower(v)] = true
+		}
+	}
+
+	return &PasswordPolicy{
+		config:    c,
+		blocklist: blocklist,
+		users:     ur,
+		history:   hr,
+		hasher:    h,
+	}
+}
+
+// Validate is check new password of the user satisfies the policy.
+// All violated rules are returned as passwordPolicyErrors, other errors are failure of checking history.
+func (p *PasswordPolicy) Validate(ctx context.Context, u *entity.User, password string) error {
+	violations := p.validateCharacters(password)
+
+	if p.config.RejectUserInfo && containsUserInfo(u, password) {
+		violations = append(violations, "Value must not contain account or name")
+	}
+	if p.config.RejectCommon && p.blocklist[strings.ToLower(password)] {
+		violations = append(violations, "Value is too common")
+	}
+
+	reused, err := p.reused(ctx, u, password)
+	if err != nil {
+		return err
+	}
+	if reused {
+		violations = append(violations, fmt.Sprintf("Value must not be the same as recent %d passwords", p.config.History))
+	}
+
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+// Check length and classes of characters
+func (p *PasswordPolicy) validateCharacters(password string) passwordPolicyErrors {
+	violations := passwordPolicyErrors{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		violations = append(violations, fmt.Sprintf("Value must be at least %d characters", p.config.MinLength))
+	}
+	if (p.config.MaxLength > 0 && length > p.config.MaxLength) || (p.hasher.MaxLength() > 0 && len(password) > p.hasher.MaxLength()) {
+		violations = append(violations, "Value is too long")
+	}
+
+	var upper, lower, digit, symbol, control, nonASCII bool
+	for _, r := range password {
+		switch {
+		case unicode.IsControl(r):
+			control = true
+		case r > unicode.MaxASCII && !p.config.AllowUnicode:
+			nonASCII = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' ':
+			symbol = true
+		}
+	}
+
+	for _, rule := range []struct {
+		violated bool
+		message  string
+	}{
+		{control, "Value must not contain control characters"},
+		{nonASCII, "Value must contain only ASCII letters, digits and symbols"},
+		{p.config.RequireUpper && !upper, "Value must contain uppercase letter"},
+		{p.config.RequireLower && !lower, "Value must contain lowercase letter"},
+		{p.config.RequireDigit && !digit, "Value must contain digit"},
+		{p.config.RequireSymbol && !symbol, "Value must contain symbol"},
+	} {
+		if rule.violated {
+			violations = append(violations, rule.message)
+		}
+	}
+	return violations
+}
+
+// Check the password is the same as current password or previous passwords
+func (p *PasswordPolicy) reused(ctx context.Context, u *entity.User, password string) (bool, error) {
+	if p.config.History <= 0 {
+		return false, nil
+	}
+
+	hashes := []string{}
+	if u.Password != "" {
+		hashes = append(hashes, u.Password)
+	}
+	if p.config.History > 1 {
+		previous, err := p.history.FindRecent(ctx, u.ID, p.config.History-1)
+		if err != nil {
+			return false, err
+		}
+		for _, h := range previous {
+			hashes = append(hashes, h.Password)
+		}
+	}
+
+	for _, h := range hashes {
+		if p.users.MatchPassword(ctx, h, password) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Expired is check the password of the user is older than max age
+func (p *PasswordPolicy) Expired(u *entity.User) bool {
+	return u.PasswordExpired(p.config.MaxAge, time.Now())
+}
+
+// Histories is number of previous passwords kept when password is changed, current password is not included
+func (p *PasswordPolicy) Histories() int {
+	if p.config.History <= 1 {
+		return 0
+	}
+	return p.config.History - 1
+}
+
+// Check the password contains account or a word of name of the user
+func containsUserInfo(u *entity.User, password string) bool {
+	password = strings.ToLower(password)
+	words := append([]string{u.Account}, strings.Fields(u.Name)...)
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= minUserInfoLength && strings.Contains(password, strings.ToLower(w)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate new password with the policy and write bad request in the same format as ValidationErrors.
+// Return false if response is already written.
+func validatePassword(c *gin.Context, p *PasswordPolicy, u *entity.User, password string) bool {
+	err := p.Validate(c.Request.Context(), u, password)
+	if err == nil {
+		return true
+	}
+
+	var perr passwordPolicyErrors
+	if errors.As(err, &perr) {
+		errorBadRequest(c, map[string]string{"newPassword": perr.Error()})
+		return false
+	}
+	errorInternalServerError(c, err)
+	return false
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Policy same as default configuration
+func newTestPasswordPolicy() *PasswordPolicy {
+	return NewPasswordPolicy(config.PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      64,
+		AllowUnicode:   true,
+		RejectUserInfo: true,
+		RejectCommon:   true,
+	}, &mock.UserRepository{}, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+}
+
+func assertViolation(t *testing.T, err error, message string) {
+	t.Helper()
+	var perr passwordPolicyErrors
+	if assert.True(t, errors.As(err, &perr), err) {
+		assert.Contains(t, perr.Error(), message)
+	}
+}
+
+func TestPasswordPolicyLength(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPasswordPolicy()
+	u := &entity.User{Account: "testuser", Name: "Test User"}
+
+	assertViolation(t, p.Validate(ctx, u, "Short1"), "at least 8")
+	assertViolation(t, p.Validate(ctx, u, "LongPassword12345678901234567890123456789012345678901234567890123"), "too long")
+	// Multibyte characters are counted as one character
+	assert.Nil(t, p.Validate(ctx, u, "パスワード変更する"))
+	assertViolation(t, p.Validate(ctx, u, "パスワード"), "at least 8")
+	// Bytes longer than the hasher is able to hash
+	assertViolation(t, p.Validate(ctx, u, "パスワードパスワードパスワードパスワードパスワード"), "too long")
+	p = NewPasswordPolicy(config.PasswordPolicy{MaxLength: 64, AllowUnicode: true}, &mock.UserRepository{}, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{})
+	assert.Nil(t, p.Validate(ctx, u, "パスワードパスワードパスワードパスワードパスワード"))
+}
+
+func TestPasswordPolicyCharacters(t *testing.T) {
+	ctx := context.Background()
+	u := &entity.User{Account: "testuser", Name: "Test User"}
+
+	p := NewPasswordPolicy(config.PasswordPolicy{
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}, &mock.UserRepository{}, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	assertViolation(t, p.Validate(ctx, u, "lower1!"), "uppercase")
+	assertViolation(t, p.Validate(ctx, u, "UPPER1!"), "lowercase")
+	assertViolation(t, p.Validate(ctx, u, "Letters!"), "digit")
+	assertViolation(t, p.Validate(ctx, u, "Letters1"), "symbol")
+	assert.Nil(t, p.Validate(ctx, u, "Letters1!"))
+	assert.Nil(t, p.Validate(ctx, u, "Letters1 "))
+	assertViolation(t, p.Validate(ctx, u, "Letters1!\t"), "control")
+
+	// All violated rules are returned at once
+	err := p.Validate(ctx, u, "lower\t")
+	assert.Equal(t, passwordPolicyErrors{
+		"Value must not contain control characters",
+		"Value must contain uppercase letter",
+		"Value must contain digit",
+		"Value must contain symbol",
+	}, err)
+
+	// ASCII only
+	p = NewPasswordPolicy(config.PasswordPolicy{}, &mock.UserRepository{}, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	assertViolation(t, p.Validate(ctx, u, "Pässword1"), "ASCII")
+	assert.Nil(t, p.Validate(ctx, u, "P@ssword1"))
+}
+
+func TestPasswordPolicyUserInfo(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPasswordPolicy()
+	u := &entity.User{Account: "testuser", Name: "Taro Yamada"}
+
+	assertViolation(t, p.Validate(ctx, u, "MyTestUser01"), "account or name")
+	assertViolation(t, p.Validate(ctx, u, "yamada2000"), "account or name")
+	assert.Nil(t, p.Validate(ctx, u, "SecretPass01"))
+
+	// Short word of name is ignored
+	u.Name = "Al Li"
+	assert.Nil(t, p.Validate(ctx, u, "Also1234"))
+}
+
+func TestPasswordPolicyCommon(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPasswordPolicy()
+	u := &entity.User{Account: "testuser", Name: "Test User"}
+
+	assertViolation(t, p.Validate(ctx, u, "Password1"), "common")
+	assertViolation(t, p.Validate(ctx, u, "qwertyuiop"), "common")
+	assert.Nil(t, p.Validate(ctx, u, "Qwertyuiop7"))
+
+	p = NewPasswordPolicy(config.PasswordPolicy{}, &mock.UserRepository{}, &mock.PasswordHistoryRepository{}, &mock.PasswordHasher{Max: 72})
+	assert.Nil(t, p.Validate(ctx, u, "password1"))
+}
+
+func TestPasswordPolicyHistory(t *testing.T) {
+	ctx := context.Background()
+	hash := func(pw string) string {
+		h, _ := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+		return string(h)
+	}
+	hr := &mock.PasswordHistoryRepository{}
+	ur := &mock.UserRepository{Histories: hr}
+	p := NewPasswordPolicy(config.PasswordPolicy{History: 3}, ur, hr, &mock.PasswordHasher{Max: 72})
+	u := &entity.User{ID: 1, Account: "testuser", Password: hash("Current01")}
+
+	assertViolation(t, p.Validate(ctx, u, "Current01"), "recent 3 passwords")
+	assert.Nil(t, p.Validate(ctx, u, "Previous01"))
+
+	// Current password is kept at changing, recent 2 passwords are kept besides current password
+	assert.Equal(t, 2, p.Histories())
+	for _, pw := range []string{"Previous01", "Previous02", "Newest01"} {
+		assert.Nil(t, ur.UpdatePassword(ctx, u, pw, p.Histories()))
+	}
+
+	assertViolation(t, p.Validate(ctx, u, "Newest01"), "recent 3 passwords")
+	assertViolation(t, p.Validate(ctx, u, "Previous02"), "recent 3 passwords")
+	assertViolation(t, p.Validate(ctx, u, "Previous01"), "recent 3 passwords")
+	// Older than recent 3 passwords
+	assert.Nil(t, p.Validate(ctx, u, "Current01"))
+	// Histories of other user
+	assert.Nil(t, p.Validate(ctx, &entity.User{ID: 2, Password: hash("Other01")}, "Previous01"))
+
+	// History is not kept if reuse is allowed
+	hr = &mock.PasswordHistoryRepository{}
+	p = NewPasswordPolicy(config.PasswordPolicy{}, &mock.UserRepository{}, hr, &mock.PasswordHasher{Max: 72})
+	assert.Equal(t, 0, p.Histories())
+	assert.Nil(t, p.Validate(ctx, u, "Newest01"))
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func postJSON(r *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+	j, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(j))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestForgotPassword(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", MailAddress: "test@example.com", IsEnable: true}
+	pr := &mock.PasswordResetRepository{}
+	ms := &mock.Mailer{}
+	h := NewPasswordHandler(&mock.UserRepository{User: user}, pr, &mock.RefreshTokenRepository{}, &mock.SessionRepository{}, &mock.AuditLogRepository{}, newTestPasswordPolicy(), ms)
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/password/forgot", h.Forgot)
+
+	// account or mail address is required
+	w := postJSON(r, "/v1/password/forgot", entity.ForgotPassword{})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+
+	// not matched user is not notified, but response is the same
+	w = postJSON(r, "/v1/password/forgot", entity.ForgotPassword{MailAddress: "other@example.com"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	w = postJSON(r, "/v1/password/forgot", entity.ForgotPassword{Account: "testuser", MailAddress: "other@example.com"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Empty(t, ms.Mails)
+
+	// unverified mail address is not notified, but response is the same
+	w = postJSON(r, "/v1/password/forgot", entity.ForgotPassword{Account: "testuser"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	assert.Empty(t, ms.Mails)
+
+	now := time.Now()
+	user.MailVerifiedAt = &now
+	w = postJSON(r, "/v1/password/forgot", entity.ForgotPassword{Account: "testuser"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+	w = postJSON(r, "/v1/password/forgot", entity.ForgotPassword{MailAddress: "test@example.com"})
+	assert.Equal(t, w.Code, http.StatusAccepted)
+
+	assert.Len(t, ms.Mails, 2)
+	assert.Equal(t, "test@example.com", ms.Mails[0].To)
+	assert.Contains(t, ms.Mails[0].Body, "reset-token-1")
+
+	reset, _ := pr.FindByToken(ctx, "reset-token-1")
+	assert.Equal(t, uint(1), reset.UserID)
+	assert.WithinDuration(t, time.Now().Add(resetTimeout), reset.ExpiredAt, time.Minute)
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: 1, Account: "testuser", IsEnable: true}
+	pr := &mock.PasswordResetRepository{}
+	tr := &mock.RefreshTokenRepository{}
+	token, _ := pr.Create(ctx, &entity.PasswordReset{UserID: 1, ExpiredAt: time.Now().Add(time.Hour)})
+	expired, _ := pr.Create(ctx, &entity.PasswordReset{UserID: 1, ExpiredAt: time.Now().Add(-time.Second)})
+	refreshToken, _ := tr.Create(ctx, &entity.RefreshToken{UserID: 1, ExpiredAt: time.Now().Add(time.Hour)})
+
+	h := NewPasswordHandler(&mock.UserRepository{User: user}, pr, tr, &mock.SessionRepository{}, &mock.AuditLogRepository{}, newTestPasswordPolicy(), &mock.Mailer{})
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/password/reset", h.Reset)
+
+	// password violating the policy does not use up the token
+	w := postJSON(r, "/v1/password/reset", entity.ResetPassword{Token: token, NewPassword: "short"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+	assert.Contains(t, w.Body.String(), "newPassword")
+	assert.Contains(t, w.Body.String(), "at least 8 characters")
+
+	for _, v := range []string{"unknown", expired} {
+		w = postJSON(r, "/v1/password/reset", entity.ResetPassword{Token: v, NewPassword: "NewPassword001"})
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		assert.Contains(t, w.Body.String(), errInvalidResetToken.Error())
+	}
+
+	w = postJSON(r, "/v1/password/reset", entity.ResetPassword{Token: token, NewPassword: "NewPassword001"})
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	// sessions before reset are revoked
+	assert.Equal(t, uint(1), user.TokenVersion)
+	rt, _ := tr.FindByToken(ctx, refreshToken)
+	assert.NotNil(t, rt.RevokedAt)
+
+	// the token is single-use
+	w = postJSON(r, "/v1/password/reset", entity.ResetPassword{Token: token, NewPassword: "NewPassword002"})
+	assert.Equal(t, w.Code, http.StatusBadRequest)
+}
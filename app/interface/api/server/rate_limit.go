@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/gotoeveryone/auth-api/app/presentation/middleware"
+)
+
+const (
+	// Max size of request body read for getting account
+	maxAccountBody int64 = 1 << 16
+)
+
+type rateLimitMiddleware struct {
+	limiter service.RateLimiter
+}
+
+// NewRateLimitMiddleware is create middleware for limiting requests
+func NewRateLimitMiddleware(l service.RateLimiter) middleware.RateLimit {
+	return &rateLimitMiddleware{
+		limiter: l,
+	}
+}
+
+// Limit is limit requests to the route by client IP and by account in request body
+func (m *rateLimitMiddleware) Limit(name string, l config.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.Burst <= 0 {
+			c.Next()
+			return
+		}
+
+		keys := []string{name + ":ip:" + c.ClientIP()}
+		if account := requestAccount(c); account != "" {
+			keys = append(keys, name+":account:"+account)
+		}
+
+		for _, key := range keys {
+			ok, wait, err := m.limiter.Take(key, l.Burst, l.Period)
+			if err != nil {
+				errorInternalServerError(c, err)
+				return
+			}
+			if !ok {
+				errorTooManyRequests(c, wait)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Get account from JSON or form request body, the body is restored for the handler
+func requestAccount(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	b, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAccountBody))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), c.Request.Body), c.Request.Body}
+	if err != nil {
+		return ""
+	}
+
+	if c.ContentType() == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(values.Get("account"))
+	}
+
+	var p struct {
+		Account string `json:"account"`
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return ""
+	}
+	// Account is not case sensitive in database
+	return strings.ToLower(p.Account)
+}
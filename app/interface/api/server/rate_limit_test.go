@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	m := NewRateLimitMiddleware(&mock.RateLimiter{})
+	r.POST("/v1/auth", m.Limit("auth", config.RateLimit{Burst: 2, Period: time.Minute}), func(c *gin.Context) {
+		// body is readable in handler
+		b, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", b)
+	})
+
+	request := func(account, ip string) *httptest.ResponseRecorder {
+		j, _ := json.Marshal(entity.Authenticate{Account: account})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", bytes.NewBuffer(j))
+		req.RemoteAddr = ip + ":12345"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := request("testuser", "192.0.2.1")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Contains(t, w.Body.String(), `"account":"testuser"`)
+	}
+
+	// limited by client IP
+	w := request("otheruser", "192.0.2.1")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	e := entity.Error{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, e.Code)
+	assert.Equal(t, errTooManyRequests.Error(), e.Message)
+
+	// limited by account from other client
+	w = request("TestUser", "192.0.2.2")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+
+	w = request("otheruser", "192.0.2.2")
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestRateLimitFormAccount(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	m := NewRateLimitMiddleware(&mock.RateLimiter{})
+	r.POST("/oauth/authorize", m.Limit("authorize", config.RateLimit{Burst: 1, Period: time.Minute}), func(c *gin.Context) {
+		c.String(http.StatusOK, c.PostForm("account"))
+	})
+
+	request := func(account, ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(url.Values{"account": {account}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":12345"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("testuser", "192.0.2.1")
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, "testuser", w.Body.String())
+
+	// limited by account from other client
+	w = request("TestUser", "192.0.2.2")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+
+	m := NewRateLimitMiddleware(&mock.RateLimiter{})
+	r.POST("/v1/auth", m.Limit("auth", config.RateLimit{}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	request := func(r *gin.Engine, forwardedFor string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/auth", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// no proxy is trusted same as router
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	m := NewRateLimitMiddleware(&mock.RateLimiter{})
+	r.POST("/v1/auth", m.Limit("auth", config.RateLimit{Burst: 1, Period: time.Minute}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := request(r, "198.51.100.1")
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	// spoofed header does not reset the bucket
+	w = request(r, "198.51.100.2")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+
+	// client behind trusted proxy is limited by forwarded address
+	_, r = gin.CreateTestContext(httptest.NewRecorder())
+	if err := r.SetTrustedProxies([]string{"192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	m = NewRateLimitMiddleware(&mock.RateLimiter{})
+	r.POST("/v1/auth", m.Limit("auth", config.RateLimit{Burst: 1, Period: time.Minute}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w = request(r, "198.51.100.1")
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = request(r, "198.51.100.2")
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = request(r, "198.51.100.1")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+}
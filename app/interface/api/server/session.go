@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/presentation/handler"
+)
+
+type sessionHandler struct {
+	repo    repository.Session
+	refresh repository.RefreshToken
+}
+
+// NewSessionHandler is create action handler for signed in session
+func NewSessionHandler(sr repository.Session, tr repository.RefreshToken) handler.Session {
+	return &sessionHandler{
+		repo:    sr,
+		refresh: tr,
+	}
+}
+
+// List is get active sessions of authenticated user
+// @Summary Return active sessions of authenticated user
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} entity.UserSession
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/sessions [get]
+func (h *sessionHandler) List(c *gin.Context) {
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	sessions, err := h.repo.FindAll(c.Request.Context(), user.ID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	current := currentSession(c)
+	res := []entity.UserSession{}
+	for _, s := range sessions {
+		res = append(res, entity.UserSession{
+			Session: s,
+			Current: s.ID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// Delete is revoke session of authenticated user, tokens issued in the session are no longer accepted
+// @Summary Revoke session
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "session ID"
+// @Success 204
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/sessions/{id} [delete]
+func (h *sessionHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorNotFound(c, errSessionNotFound)
+		return
+	}
+
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	// Session of other user is treated as not found
+	s, err := h.repo.Find(c.Request.Context(), uint(id))
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if s == nil || s.UserID != user.ID || !s.Active(time.Now()) {
+		errorNotFound(c, errSessionNotFound)
+		return
+	}
+
+	if err := revokeSession(c.Request.Context(), h.repo, h.refresh, s); err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteOthers is revoke all sessions of authenticated user except the current session
+// @Summary Revoke all other sessions
+// @Tags Authenticate
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 204
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Router /v1/me/sessions [delete]
+func (h *sessionHandler) DeleteOthers(c *gin.Context) {
+	identity, _ := c.Get(config.IdentityKey)
+	user := identity.(*entity.User)
+
+	sessions, err := h.repo.FindAll(c.Request.Context(), user.ID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	current := currentSession(c)
+	for i := range sessions {
+		if sessions[i].ID == current {
+			continue
+		}
+		if err := revokeSession(c.Request.Context(), h.repo, h.refresh, &sessions[i]); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Get ID of the session in which the request is made, zero if it is authenticated without session
+func currentSession(c *gin.Context) uint {
+	sid, _ := jwt.ExtractClaims(c)[config.SessionKey].(float64)
+	return uint(sid)
+}
+
+// Revoke session and refresh tokens of the session, it is no longer continued
+func revokeSession(ctx context.Context, sr repository.Session, tr repository.RefreshToken, s *entity.Session) error {
+	if err := sr.Revoke(ctx, s); err != nil {
+		return err
+	}
+	return tr.RevokeFamily(ctx, s.FamilyID)
+}
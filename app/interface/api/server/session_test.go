@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Set claims of the token issued in the session
+func setSession(sid uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("JWT_PAYLOAD", jwt.MapClaims{config.SessionKey: float64(sid)})
+		c.Next()
+	}
+}
+
+func newTestSessions(t *testing.T) *mock.SessionRepository {
+	ctx := context.Background()
+	sr := &mock.SessionRepository{}
+	for _, userID := range []uint{1, 1, 2, 1} {
+		if err := sr.Create(ctx, &entity.Session{
+			UserID:    userID,
+			FamilyID:  config.RandomString(16),
+			IPAddress: "192.0.2.1",
+			UserAgent: "test",
+			ExpiredAt: time.Now().Add(time.Hour),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sr
+}
+
+func TestListSessions(t *testing.T) {
+	h := NewSessionHandler(newTestSessions(t), &mock.RefreshTokenRepository{})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.Use(setIdentity(&entity.User{ID: 1}), setSession(2))
+	r.GET("/v1/me/sessions", h.List)
+
+	req, _ := http.NewRequest("GET", "/v1/me/sessions", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	e := []entity.UserSession{}
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, e, 3)
+	assert.Equal(t, []bool{false, true, false}, []bool{e[0].Current, e[1].Current, e[2].Current})
+	assert.Equal(t, "192.0.2.1", e[0].IPAddress)
+	assert.Equal(t, "test", e[0].UserAgent)
+	assert.NotContains(t, w.Body.String(), "familyId")
+}
+
+func TestDeleteSession(t *testing.T) {
+	ctx := context.Background()
+	sr := newTestSessions(t)
+	tr := &mock.RefreshTokenRepository{}
+	s, _ := sr.Find(ctx, 1)
+	token, _ := tr.Create(ctx, &entity.RefreshToken{UserID: 1, FamilyID: s.FamilyID, ExpiredAt: time.Now().Add(time.Hour)})
+	h := NewSessionHandler(sr, tr)
+
+	for _, id := range []string{"3", "5", "invalid"} {
+		// session of other user or unknown
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}), setSession(2))
+		r.DELETE("/v1/me/sessions/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/me/sessions/"+id, nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, id)
+	}
+	{
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.Use(setIdentity(&entity.User{ID: 1}), setSession(2))
+		r.DELETE("/v1/me/sessions/:id", h.Delete)
+
+		req, _ := http.NewRequest("DELETE", "/v1/me/sessions/1", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		s, _ := sr.Find(ctx, 1)
+		assert.NotNil(t, s.RevokedAt)
+		rt, _ := tr.FindByToken(ctx, token)
+		assert.NotNil(t, rt.RevokedAt)
+
+		// already revoked
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeleteOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	sr := newTestSessions(t)
+	h := NewSessionHandler(sr, &mock.RefreshTokenRepository{})
+
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.Use(setIdentity(&entity.User{ID: 1}), setSession(2))
+	r.DELETE("/v1/me/sessions", h.DeleteOthers)
+
+	req, _ := http.NewRequest("DELETE", "/v1/me/sessions", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	sessions, _ := sr.FindAll(ctx, 1)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, uint(2), sessions[0].ID)
+
+	// sessions of other user are not affected
+	sessions, _ = sr.FindAll(ctx, 2)
+	assert.Len(t, sessions, 1)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// TokenRefreshHandler is exchange refresh token for new access token and refresh token
+// @Summary Exchange refresh token for new access token and refresh token
+// @Tags Authenticate
+// @Accept  json
+// @Produce json
+// @Param data body entity.Refresh true "request data"
+// @Success 200 {object} entity.Claim
+// @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 404 {object} entity.Error
+// @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
+// @Router /v1/token/refresh [post]
+func (mw *jwtMiddleware) TokenRefreshHandler(c *gin.Context) {
+	var p entity.Refresh
+	if err := c.ShouldBindJSON(&p); err != nil {
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			errorBadRequest(c, ValidationErrors(verr, &p))
+			return
+		}
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
+	t, err := mw.refresh.FindByToken(c.Request.Context(), p.RefreshToken)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if t == nil || t.RevokedAt != nil || !t.ExpiredAt.After(mw.TimeFunc()) {
+		errorUnauthorized(c, errInvalidRefreshToken)
+		return
+	}
+
+	// Reusing rotated token means it may be stolen, so revoke the session and all tokens of the family
+	used, err := mw.refresh.Use(c.Request.Context(), t)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if !used {
+		session, err := mw.sessions.FindByFamily(c.Request.Context(), t.FamilyID)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if session != nil {
+			err = revokeSession(c.Request.Context(), mw.sessions, mw.refresh, session)
+		} else {
+			err = mw.refresh.RevokeFamily(c.Request.Context(), t.FamilyID)
+		}
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		errorUnauthorized(c, errInvalidRefreshToken)
+		return
+	}
+
+	user, err := mw.repo.Find(c.Request.Context(), t.UserID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if user == nil || !user.Valid() {
+		errorUnauthorized(c, errInvalidAccount)
+		return
+	}
+	// Refreshing is not a way around changing password
+	if err := mw.verifyPasswordState(user); err != nil {
+		errorUnauthorized(c, err)
+		return
+	}
+
+	session, err := mw.sessions.FindByFamily(c.Request.Context(), t.FamilyID)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+	if session == nil {
+		// Refresh token issued before supporting session continues as new session
+		if session, err = mw.newSession(c, user, t.FamilyID, "", ""); err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+	} else if !session.Active(mw.TimeFunc()) {
+		errorUnauthorized(c, errInvalidRefreshToken)
+		return
+	}
+	// Session authorized for OAuth client ends when the client is disabled or deleted
+	if session.ClientID != "" {
+		enabled, err := mw.clientEnabled(c.Request.Context(), session.ClientID)
+		if err != nil {
+			errorInternalServerError(c, err)
+			return
+		}
+		if !enabled {
+			errorUnauthorized(c, errInvalidRefreshToken)
+			return
+		}
+	}
+
+	token, expire, refreshToken, err := mw.continueSession(c.Request.Context(), session, user)
+	if err != nil {
+		errorInternalServerError(c, err)
+		return
+	}
+
+	loginResponse(c, http.StatusOK, token, expire, refreshToken)
+}
+
+// Start new session of the user and issue token and refresh token bound to it.
+// Client ID is empty for login of the user, otherwise the session is authorized for the OAuth client with the scope.
+func (mw *jwtMiddleware) startSession(c *gin.Context, user *entity.User, clientID, scope string) (string, time.Time, string, error) {
+	familyID, err := config.RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+	session, err := mw.newSession(c, user, familyID, clientID, scope)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+	return mw.continueSession(c.Request.Context(), session, user)
+}
+
+// Create session with family of refresh tokens, the client is recorded from the request
+func (mw *jwtMiddleware) newSession(c *gin.Context, user *entity.User, familyID, clientID, scope string) (*entity.Session, error) {
+	session := &entity.Session{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ClientID:  clientID,
+		Scope:     scope,
+		IPAddress: c.ClientIP(),
+		UserAgent: userAgent(c),
+		ExpiredAt: mw.TimeFunc().Add(refreshTimeout),
+	}
+	if err := mw.sessions.Create(c.Request.Context(), session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Issue token and refresh token bound to the session, the session is extended until the refresh token expires.
+// Token of the session authorized for OAuth client is typed and limited to the granted scope.
+func (mw *jwtMiddleware) continueSession(ctx context.Context, session *entity.Session, user *entity.User) (string, time.Time, string, error) {
+	claims := gojwt.MapClaims{}
+	for key, value := range mw.PayloadFunc(user) {
+		claims[key] = value
+	}
+	if session.ClientID != "" {
+		claims[tokenTypeKey] = tokenTypeOAuth
+		claims["client_id"] = session.ClientID
+		claims["scope"] = session.Scope
+	}
+	claims[config.SessionKey] = session.ID
+	token, expire, err := mw.sign(claims, mw.Timeout)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	refreshToken, err := mw.issueRefreshToken(ctx, user, session.FamilyID)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	now := mw.TimeFunc()
+	session.TokenID, _ = claims["jti"].(string)
+	session.LastSeenAt = &now
+	session.ExpiredAt = now.Add(refreshTimeout)
+	if err := mw.sessions.Update(ctx, session); err != nil {
+		return "", time.Time{}, "", err
+	}
+	return token, expire, refreshToken, nil
+}
+
+// Issue refresh token for user in the family
+func (mw *jwtMiddleware) issueRefreshToken(ctx context.Context, user *entity.User, familyID string) (string, error) {
+	return mw.refresh.Create(ctx, &entity.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ExpiredAt: mw.TimeFunc().Add(refreshTimeout),
+	})
+}
+
+// TokenGenerator is create token signed with key set for user
+func (mw *jwtMiddleware) TokenGenerator(data any) (string, time.Time, error) {
+	claims := gojwt.MapClaims{}
+	if mw.PayloadFunc != nil {
+		for key, value := range mw.PayloadFunc(data) {
+			claims[key] = value
+		}
+	}
+	return mw.sign(claims, mw.Timeout)
+}
+
+// Sign claims with new token ID and expiration
+func (mw *jwtMiddleware) sign(claims gojwt.MapClaims, d time.Duration) (string, time.Time, error) {
+	jti, err := config.RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := mw.TimeFunc()
+	expire := now.Add(d)
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = expire.Unix()
+	claims["orig_iat"] = now.Unix()
+	// Issuer and audience are kept if specified, such as ID token for the client
+	if _, ok := claims["iss"]; !ok && mw.config.Issuer != "" {
+		claims["iss"] = mw.config.Issuer
+	}
+	if _, ok := claims["aud"]; !ok && mw.config.Audience != "" {
+		claims["aud"] = mw.config.Audience
+	}
+
+	token, err := mw.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expire, nil
+}
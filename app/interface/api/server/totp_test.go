@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Secret of test vectors in RFC 6238, ASCII "12345678901234567890"
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := totpCode(testTOTPSecret, unix/totpPeriod)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code)
+	}
+
+	_, err := totpCode("invalid!", 1)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	code, _ := totpCode(testTOTPSecret, step)
+	s, ok := verifyTOTP(testTOTPSecret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, step, s)
+
+	// clock drift within a step
+	code, _ = totpCode(testTOTPSecret, step-1)
+	s, ok = verifyTOTP(testTOTPSecret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, s)
+
+	code, _ = totpCode(testTOTPSecret, step+2)
+	_, ok = verifyTOTP(testTOTPSecret, code, now)
+	assert.False(t, ok)
+
+	_, ok = verifyTOTP(testTOTPSecret, "000000", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPSecret(t *testing.T) {
+	s, err := totpSecret()
+	assert.Nil(t, err)
+	assert.Len(t, s, 32)
+	assert.NotContains(t, s, "=")
+
+	_, err = totpCode(s, 1)
+	assert.Nil(t, err)
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(totpURI("auth-api", "testuser", testTOTPSecret))
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/auth-api:testuser", u.Path)
+	assert.Equal(t, testTOTPSecret, u.Query().Get("secret"))
+	assert.Equal(t, "auth-api", u.Query().Get("issuer"))
+	assert.True(t, strings.HasPrefix(u.String(), "otpauth://totp/"))
+}
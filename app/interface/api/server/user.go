@@ -10,32 +10,51 @@ import (
 	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
 	"github.com/gotoeveryone/auth-api/app/presentation/handler"
-	"github.com/rs/zerolog/log"
 )
 
 type userHandler struct {
-	repo repository.User
+	repo          repository.User
+	invitations   repository.Invitation
+	verifications repository.MailVerification
+	audit         repository.AuditLog
+	mailer        service.Mailer
+	// Registration mode, one of config.Registration* constants
+	registration string
 }
 
 // NewUserHandler is create action handler for user
-func NewUserHandler(ur repository.User) handler.User {
+func NewUserHandler(ur repository.User, ir repository.Invitation, vr repository.MailVerification, al repository.AuditLog, m service.Mailer, registration string) handler.User {
 	return &userHandler{
-		repo: ur,
+		repo:          ur,
+		invitations:   ir,
+		verifications: vr,
+		audit:         al,
+		mailer:        m,
+		registration:  registration,
 	}
 }
 
-// Register is execute registration of account
+// Register is execute registration of account.
+// Only administrator is able to assign role, other users are registered as general user
+// if registration is open or they have invitation code.
+// Token for verifying the mail address is sent to the address.
 // @Summary Execute registration of account
 // @Tags Authenticate
+// @Security ApiKeyAuth
 // @Accept  json
 // @Produce json
 // @Param data body entity.RegistrationUser true "request data"
 // @Success 201 {object} entity.GeneratedPassword
 // @Failure 400 {object} entity.Error
+// @Failure 401 {object} entity.Error
+// @Failure 403 {object} entity.Error
 // @Failure 404 {object} entity.Error
 // @Failure 405 {object} entity.Error
+// @Failure 429 {object} entity.Error
 // @Router /v1/users [post]
+// @Router /v1/admin/users [post]
 func (h *userHandler) Register(c *gin.Context) {
 	var p entity.RegistrationUser
 	if err := c.ShouldBindJSON(&p); err != nil {
@@ -48,8 +67,30 @@ func (h *userHandler) Register(c *gin.Context) {
 		return
 	}
 
+	admin := isAdministrator(c)
+	if !admin {
+		if h.registration != config.RegistrationOpen && h.registration != config.RegistrationInvite {
+			errorForbidden(c, errPermissionDenied)
+			return
+		}
+		if p.Role != nil && entity.Role(*p.Role) != entity.RoleGeneral {
+			errorForbidden(c, errPermissionDenied)
+			return
+		}
+		if h.registration == config.RegistrationInvite && p.InviteCode == "" {
+			errorBadRequest(c, errInvalidInvitation)
+			return
+		}
+	}
+
+	t, err := time.Parse("2006-01-02", p.Birthday)
+	if err != nil {
+		errorBadRequest(c, errValidationFailed)
+		return
+	}
+
 	// Check the same account already exists
-	if res, err := h.repo.Exists(p.Account); err != nil {
+	if res, err := h.repo.Exists(c.Request.Context(), p.Account); err != nil {
 		errorInternalServerError(c, err)
 		return
 	} else if res {
@@ -57,10 +98,15 @@ func (h *userHandler) Register(c *gin.Context) {
 		return
 	}
 
-	t, err := time.Parse(p.Birthday, "2006-01-02")
-	if err != nil {
-		errorInternalServerError(c, err)
-		return
+	// Consume invitation code, it is not used again even if creating user failed
+	if !admin && h.registration == config.RegistrationInvite {
+		if ok, err := h.invitations.Use(c.Request.Context(), p.InviteCode); err != nil {
+			errorInternalServerError(c, err)
+			return
+		} else if !ok {
+			errorBadRequest(c, errInvalidInvitation)
+			return
+		}
 	}
 
 	u := entity.User{
@@ -71,77 +117,26 @@ func (h *userHandler) Register(c *gin.Context) {
 		Birthday:    entity.Date{Time: t},
 	}
 
-	if p.Role != nil {
+	if admin && p.Role != nil {
 		u.Role = entity.Role(*p.Role)
 	}
 
-	pass, err := h.repo.Create(&u)
+	pass, err := h.repo.Create(c.Request.Context(), &u)
 	if err != nil {
 		errorInternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, entity.GeneratedPassword{
-		Password: pass,
-	})
-}
-
-// Activate is enable account with update password
-// @Summary Enable account with update password
-// @Tags Authenticate
-// @Accept  json
-// @Produce json
-// @Param data body entity.Activate true "request data"
-// @Success 200
-// @Failure 400 {object} entity.Error
-// @Failure 404 {object} entity.Error
-// @Failure 405 {object} entity.Error
-// @Router /v1/activate [post]
-func (h *userHandler) Activate(c *gin.Context) {
-	// Execute validation
-	var a entity.Activate
-	if err := c.ShouldBindJSON(&a); err != nil {
-		var verr validator.ValidationErrors
-		if errors.As(err, &verr) {
-			errorBadRequest(c, ValidationErrors(verr, &a))
-			return
-		}
-		errorBadRequest(c, errValidationFailed)
-		return
-	}
-
-	// Deny change to same password
-	if a.Password == a.NewPassword {
-		errorBadRequest(c, errSamePassword)
-		return
-	}
-
-	// Search user
-	user, err := h.repo.FindByAccount(a.Account)
-	if err != nil {
+	if err := sendVerificationMail(c.Request.Context(), h.verifications, h.mailer, &u); err != nil {
 		errorInternalServerError(c, err)
 		return
 	}
-	if user == nil {
-		errorUnauthorized(c, errUnauthorized)
-		return
-	}
-
-	// Check password matching from user has password
-	if err := h.repo.MatchPassword(user.Password, a.Password); err != nil {
-		log.Fatal().Err(err)
-		errorUnauthorized(c, errUnauthorized)
-		return
-	}
 
-	// Enable account with update password
-	user.IsEnable = true
-	if err := h.repo.UpdatePassword(user, a.NewPassword); err != nil {
-		errorInternalServerError(c, err)
-		return
-	}
+	recordAudit(h.audit, c, auditEvent(entity.AuditRegistered, actor(c), &u, string(u.Role)))
 
-	c.JSON(http.StatusOK, gin.H{})
+	c.JSON(http.StatusCreated, entity.GeneratedPassword{
+		Password: pass,
+	})
 }
 
 // Identity is get authenticated user
@@ -160,3 +155,12 @@ func (h *userHandler) Identity(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// Check the authenticated user is administrator
+func isAdministrator(c *gin.Context) bool {
+	identity, _ := c.Get(config.IdentityKey)
+	if v, ok := identity.(*entity.User); ok {
+		return v.Role == entity.RoleAdministrator
+	}
+	return false
+}
@@ -2,10 +2,12 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gotoeveryone/auth-api/app/config"
@@ -25,7 +27,7 @@ func TestRegistrationFailed(t *testing.T) {
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
 
-	h := NewUserHandler(&mock.UserRepository{})
+	h := NewUserHandler(&mock.UserRepository{}, &mock.InvitationRepository{}, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{}, config.RegistrationAdmin)
 	r.POST("/v1/users", h.Register)
 
 	p := entity.User{
@@ -45,8 +47,10 @@ func TestRegistrationFailed(t *testing.T) {
 func TestRegistrationSuccess(t *testing.T) {
 	w := httptest.NewRecorder()
 	_, r := gin.CreateTestContext(w)
+	r.Use(setIdentity(&entity.User{ID: 1, Role: entity.RoleAdministrator}))
 
-	h := NewUserHandler(&mock.UserRepository{})
+	ms := &mock.Mailer{}
+	h := NewUserHandler(&mock.UserRepository{}, &mock.InvitationRepository{}, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, ms, config.RegistrationAdmin)
 	r.POST("/v1/users", h.Register)
 
 	role := "General"
@@ -73,134 +77,105 @@ func TestRegistrationSuccess(t *testing.T) {
 		t.Error(err)
 	}
 	assert.NotEmpty(t, e.Password)
-}
 
-func TestActivateFailedInvalidParam(t *testing.T) {
-	w := httptest.NewRecorder()
-	_, r := gin.CreateTestContext(w)
-
-	h := NewUserHandler(&mock.UserRepository{})
-	r.POST("/v1/activate", h.Activate)
+	// token for verifying mail address is sent
+	assert.Len(t, ms.Mails, 1)
+	assert.Equal(t, "hoge@example.com", ms.Mails[0].To)
+	assert.Contains(t, ms.Mails[0].Body, "verification-token-1")
+}
 
-	p := entity.Activate{
-		Authenticate: entity.Authenticate{
-			Account:  "testuser",
-			Password: "password",
-		},
+func registerUser(r *gin.Engine, role, inviteCode string) *httptest.ResponseRecorder {
+	p := entity.RegistrationUser{
+		Account:     "testuser",
+		Name:        "Test User",
+		Gender:      "Unknown",
+		MailAddress: "hoge@example.com",
+		Birthday:    "2000-01-31",
+		InviteCode:  inviteCode,
 	}
-	j, err := json.Marshal(p)
-	if err != nil {
-		t.Error(err)
+	if role != "" {
+		p.Role = &role
 	}
-	body := bytes.NewBuffer(j)
-	req, _ := http.NewRequest("POST", "/v1/activate", body)
-	r.ServeHTTP(w, req)
+	j, _ := json.Marshal(p)
 
-	assert.Equal(t, w.Code, http.StatusBadRequest)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/users", bytes.NewBuffer(j))
+	r.ServeHTTP(w, req)
+	return w
 }
 
-func TestActivateFailedSamePassword(t *testing.T) {
-	w := httptest.NewRecorder()
-	_, r := gin.CreateTestContext(w)
+func TestRegistrationAdminOnly(t *testing.T) {
+	h := NewUserHandler(&mock.UserRepository{}, &mock.InvitationRepository{}, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{}, config.RegistrationAdmin)
 
-	h := NewUserHandler(&mock.UserRepository{})
-	r.POST("/v1/activate", h.Activate)
+	{
+		_, r := gin.CreateTestContext(httptest.NewRecorder())
+		r.POST("/v1/users", h.Register)
 
-	p := entity.Activate{
-		Authenticate: entity.Authenticate{
-			Account:  "testuser",
-			Password: "password",
-		},
-		NewPassword: "password",
+		w := registerUser(r, "", "")
+		assert.Equal(t, w.Code, http.StatusForbidden)
 	}
-	j, err := json.Marshal(p)
-	if err != nil {
-		t.Error(err)
+	{
+		_, r := gin.CreateTestContext(httptest.NewRecorder())
+		r.Use(setIdentity(&entity.User{ID: 1, Role: entity.RoleGeneral}))
+		r.POST("/v1/users", h.Register)
+
+		w := registerUser(r, "", "")
+		assert.Equal(t, w.Code, http.StatusForbidden)
 	}
-	body := bytes.NewBuffer(j)
-	req, _ := http.NewRequest("POST", "/v1/activate", body)
-	r.ServeHTTP(w, req)
+	{
+		_, r := gin.CreateTestContext(httptest.NewRecorder())
+		r.Use(setIdentity(&entity.User{ID: 1, Role: entity.RoleAdministrator}))
+		r.POST("/v1/users", h.Register)
 
-	assert.Equal(t, w.Code, http.StatusBadRequest)
+		w := registerUser(r, "Administrator", "")
+		assert.Equal(t, w.Code, http.StatusCreated)
+	}
 }
 
-func TestActivateFailedAccountNotExist(t *testing.T) {
-	w := httptest.NewRecorder()
-	_, r := gin.CreateTestContext(w)
+func TestRegistrationOpen(t *testing.T) {
+	h := NewUserHandler(&mock.UserRepository{}, &mock.InvitationRepository{}, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{}, config.RegistrationOpen)
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/users", h.Register)
 
-	h := NewUserHandler(&mock.UserRepository{})
-	r.POST("/v1/activate", h.Activate)
+	// role is not assignable
+	w := registerUser(r, "Administrator", "")
+	assert.Equal(t, w.Code, http.StatusForbidden)
 
-	p := entity.Activate{
-		Authenticate: entity.Authenticate{
-			Account:  "testuser",
-			Password: "HogeFuga001",
-		},
-		NewPassword: "HogeFuga001New",
-	}
-	j, err := json.Marshal(p)
-	if err != nil {
-		t.Error(err)
-	}
-	body := bytes.NewBuffer(j)
-	req, _ := http.NewRequest("POST", "/v1/activate", body)
-	r.ServeHTTP(w, req)
+	w = registerUser(r, "General", "")
+	assert.Equal(t, w.Code, http.StatusCreated)
 
-	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	w = registerUser(r, "", "")
+	assert.Equal(t, w.Code, http.StatusCreated)
 }
 
-func TestActivateFailedPasswordNotMatched(t *testing.T) {
-	w := httptest.NewRecorder()
-	_, r := gin.CreateTestContext(w)
+func TestRegistrationInvite(t *testing.T) {
+	ctx := context.Background()
+	ir := &mock.InvitationRepository{}
+	code, _ := ir.Create(ctx, &entity.Invitation{ExpiredAt: time.Now().Add(time.Hour)})
+	expired, _ := ir.Create(ctx, &entity.Invitation{ExpiredAt: time.Now().Add(-time.Hour)})
 
-	h := NewUserHandler(&mock.UserRepository{User: &entity.User{
-		Account: "testuser",
-	}, IsMatchPassword: false})
-	r.POST("/v1/activate", h.Activate)
-
-	p := entity.Activate{
-		Authenticate: entity.Authenticate{
-			Account:  "testuser",
-			Password: "HogeFuga001",
-		},
-		NewPassword: "HogeFuga001New",
-	}
-	j, err := json.Marshal(p)
-	if err != nil {
-		t.Error(err)
-	}
-	body := bytes.NewBuffer(j)
-	req, _ := http.NewRequest("POST", "/v1/activate", body)
-	r.ServeHTTP(w, req)
+	h := NewUserHandler(&mock.UserRepository{}, ir, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{}, config.RegistrationInvite)
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.POST("/v1/users", h.Register)
 
-	assert.Equal(t, w.Code, http.StatusUnauthorized)
-}
+	w := registerUser(r, "", "")
+	assert.Equal(t, w.Code, http.StatusBadRequest)
 
-func TestActivateSuccess(t *testing.T) {
-	w := httptest.NewRecorder()
-	_, r := gin.CreateTestContext(w)
+	w = registerUser(r, "", "unknown")
+	assert.Equal(t, w.Code, http.StatusBadRequest)
 
-	h := NewUserHandler(&mock.UserRepository{User: &entity.User{
-		Account: "testuser",
-	}, IsMatchPassword: true})
-	r.POST("/v1/activate", h.Activate)
-
-	p := entity.Activate{
-		Authenticate: entity.Authenticate{
-			Account:  "testuser",
-			Password: "HogeFuga001",
-		},
-		NewPassword: "HogeFuga001New",
-	}
-	j, err := json.Marshal(p)
-	if err != nil {
-		t.Error(err)
-	}
-	body := bytes.NewBuffer(j)
-	req, _ := http.NewRequest("POST", "/v1/activate", body)
-	r.ServeHTTP(w, req)
+	w = registerUser(r, "", expired)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
 
-	assert.Equal(t, w.Code, http.StatusOK)
+	w = registerUser(r, "Administrator", code)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	w = registerUser(r, "", code)
+	assert.Equal(t, w.Code, http.StatusCreated)
+
+	// the code is single-use
+	w = registerUser(r, "", code)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
 }
 
 func TestIdentity(t *testing.T) {
@@ -209,7 +184,7 @@ func TestIdentity(t *testing.T) {
 	u := entity.User{Account: "testuser"}
 	r.Use(setIdentity(&u))
 
-	h := NewUserHandler(&mock.UserRepository{})
+	h := NewUserHandler(&mock.UserRepository{}, &mock.InvitationRepository{}, &mock.MailVerificationRepository{}, &mock.AuditLogRepository{}, &mock.Mailer{}, config.RegistrationAdmin)
 	r.GET("/v1/me", h.Identity)
 
 	req, _ := http.NewRequest("GET", "/v1/me", nil)
@@ -2,30 +2,69 @@ package server
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
 )
 
 var (
-	dateRegex     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
-	passwordRegex = regexp.MustCompile(`^[A-Za-z0-9]{8,}$`)
+	dateRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	// Space-delimited scope tokens of OAuth (RFC 6749 section 3.3)
+	scopeRegex = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+( [\x21\x23-\x5B\x5D-\x7E]+)*$`)
 )
 
 func date(fl validator.FieldLevel) bool {
 	return dateRegex.MatchString(fl.Field().String())
 }
 
-func password(fl validator.FieldLevel) bool {
-	return passwordRegex.MatchString(fl.Field().String())
+func scope(fl validator.FieldLevel) bool {
+	return scopeRegex.MatchString(fl.Field().String())
+}
+
+// Scope of personal API key must consist of scopes of the API
+func apiScope(fl validator.FieldLevel) bool {
+	if !scopeRegex.MatchString(fl.Field().String()) {
+		return false
+	}
+	for _, s := range strings.Fields(fl.Field().String()) {
+		found := false
+		for _, a := range entity.APIScopes {
+			if s == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Redirect URI of OAuth client must be absolute URI without fragment (RFC 6749 section 3.1.2).
+// Custom scheme is allowed for mobile app.
+func redirectURI(fl validator.FieldLevel) bool {
+	u, err := url.Parse(fl.Field().String())
+	if err != nil || u.Scheme == "" || u.Fragment != "" || u.Opaque != "" {
+		return false
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return false
+	}
+	return true
 }
 
 func init() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("date", date)
-		v.RegisterValidation("password", password)
+		v.RegisterValidation("scope", scope)
+		v.RegisterValidation("api_scope", apiScope)
+		v.RegisterValidation("redirect_uri", redirectURI)
 	}
 }
 
@@ -36,14 +36,6 @@ func TestActivateValidate(t *testing.T) {
 		messages := ValidationErrors(errs, &a)
 		assert.Contains(t, messages["newPassword"], "required")
 	}
-
-	// password
-	a.NewPassword = "hogefug"
-	if err := binding.Validator.ValidateStruct(a); err != nil {
-		errs := err.(validator.ValidationErrors)
-		messages := ValidationErrors(errs, &a)
-		assert.Contains(t, messages["newPassword"], "invalid")
-	}
 }
 
 func TestAuthenticateValidate(t *testing.T) {
@@ -72,12 +64,48 @@ func TestAuthenticateValidate(t *testing.T) {
 		messages := ValidationErrors(errs, &a)
 		assert.Contains(t, messages["account"], "max")
 	}
+}
+
+func TestCreateClientValidate(t *testing.T) {
+	for scope, valid := range map[string]bool{
+		"":                 true,
+		"read":             true,
+		"read write:users": true,
+		"read  write":      false,
+		" read":            false,
+		"read\"":           false,
+		"read\\write":      false,
+		"read\twrite":      false,
+	} {
+		a := entity.CreateClient{Name: "test", Scope: scope}
+		err := binding.Validator.ValidateStruct(a)
+		if valid {
+			assert.Nil(t, err, scope)
+			continue
+		}
+		if assert.NotNil(t, err, scope) {
+			messages := ValidationErrors(err.(validator.ValidationErrors), &a)
+			assert.Contains(t, messages["scope"], "invalid")
+		}
+	}
+}
 
-	// password
-	a.Password = "hogefug"
-	if err := binding.Validator.ValidateStruct(a); err != nil {
-		errs := err.(validator.ValidationErrors)
-		messages := ValidationErrors(errs, &a)
-		assert.Contains(t, messages["password"], "invalid")
+func TestClientRedirectURIsValidate(t *testing.T) {
+	for uri, valid := range map[string]bool{
+		"https://example.com/callback":     true,
+		"http://localhost:3000/callback":   true,
+		"com.example.app:/oauth/callback":  true,
+		"/callback":                        false,
+		"https:///callback":                false,
+		"https://example.com/callback#top": false,
+		"mailto:test@example.com":          false,
+	} {
+		a := entity.CreateClient{Name: "test", RedirectURIs: []string{uri}}
+		err := binding.Validator.ValidateStruct(a)
+		if valid {
+			assert.Nil(t, err, uri)
+		} else {
+			assert.NotNil(t, err, uri)
+		}
 	}
 }
@@ -43,7 +43,13 @@ func main() {
 	}
 
 	c := config.App{
-		Debug: isDebug,
+		Debug:        isDebug,
+		Registration: config.GetenvOrDefault("REGISTRATION_MODE", config.RegistrationAdmin),
+		MFAIssuer:    config.GetenvOrDefault("MFA_ISSUER", "auth-api"),
+
+		RequireMailVerification: config.GetenvBool("REQUIRE_MAIL_VERIFICATION", false),
+		TrustedProxies:          config.GetenvList("TRUSTED_PROXIES"),
+		OAuth:                   config.GetenvBool("OAUTH_ENABLED", false),
 		DB: config.DB{
 			Host:     config.GetenvOrDefault("DATABASE_HOST", "127.0.0.1"),
 			Port:     config.GetenvOrDefault("DATABASE_PORT", "3306"),
@@ -51,16 +57,72 @@ func main() {
 			User:     config.GetenvOrDefault("DATABASE_USER", "auth_api"),
 			Password: config.GetenvOrDefault("DATABASE_PASSWORD", ""),
 			Timezone: time.Local,
+
+			QueryTimeout: config.GetenvDuration("DATABASE_QUERY_TIMEOUT", 10*time.Second),
+		},
+		JWT: config.JWT{
+			Algorithm:      config.GetenvOrDefault("JWT_ALGORITHM", ""),
+			SecretKey:      config.GetenvOrDefault("SECRET_KEY", ""),
+			PrivateKeyFile: config.GetenvOrDefault("JWT_PRIVATE_KEY_FILE", ""),
+
+			VerificationSecretKeys: config.GetenvList("JWT_VERIFICATION_SECRET_KEYS"),
+			VerificationKeyFiles:   config.GetenvList("JWT_VERIFICATION_KEY_FILES"),
+
+			Issuer:   config.GetenvOrDefault("JWT_ISSUER", ""),
+			Audience: config.GetenvOrDefault("JWT_AUDIENCE", ""),
+		},
+		Lockout: config.Lockout{
+			Threshold: config.GetenvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			Duration:  config.GetenvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		},
+		RateLimits: config.RateLimits{
+			Auth:       config.GetenvRateLimit("RATE_LIMIT_AUTH", config.RateLimit{Burst: 10, Period: time.Minute}),
+			Activate:   config.GetenvRateLimit("RATE_LIMIT_ACTIVATE", config.RateLimit{Burst: 5, Period: time.Minute}),
+			Register:   config.GetenvRateLimit("RATE_LIMIT_REGISTER", config.RateLimit{Burst: 5, Period: time.Minute}),
+			Password:   config.GetenvRateLimit("RATE_LIMIT_PASSWORD", config.RateLimit{Burst: 5, Period: time.Minute}),
+			MailVerify: config.GetenvRateLimit("RATE_LIMIT_MAIL_VERIFY", config.RateLimit{Burst: 5, Period: time.Minute}),
+			Refresh:    config.GetenvRateLimit("RATE_LIMIT_REFRESH", config.RateLimit{Burst: 30, Period: time.Minute}),
+			Introspect: config.GetenvRateLimit("RATE_LIMIT_INTROSPECT", config.RateLimit{Burst: 120, Period: time.Minute}),
+		},
+		Mail: config.Mail{
+			Driver:   config.GetenvOrDefault("MAIL_DRIVER", config.MailDriverSMTP),
+			Host:     config.GetenvOrDefault("MAIL_HOST", ""),
+			Port:     config.GetenvOrDefault("MAIL_PORT", "25"),
+			User:     config.GetenvOrDefault("MAIL_USER", ""),
+			Password: config.GetenvOrDefault("MAIL_PASSWORD", ""),
+			From:     config.GetenvOrDefault("MAIL_FROM", "no-reply@localhost"),
+			Dir:      config.GetenvOrDefault("MAIL_DIR", ""),
+		},
+		PasswordHash: config.PasswordHash{
+			Algorithm:         config.GetenvOrDefault("PASSWORD_HASH_ALGORITHM", config.PasswordHashBcrypt),
+			BcryptCost:        config.GetenvInt("PASSWORD_BCRYPT_COST", 10),
+			Argon2Memory:      uint32(config.GetenvInt("PASSWORD_ARGON2_MEMORY", 64*1024)),
+			Argon2Iterations:  uint32(config.GetenvInt("PASSWORD_ARGON2_ITERATIONS", 3)),
+			Argon2Parallelism: uint8(config.GetenvInt("PASSWORD_ARGON2_PARALLELISM", 4)),
+		},
+		PasswordPolicy: config.PasswordPolicy{
+			MinLength:      config.GetenvInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:      config.GetenvInt("PASSWORD_MAX_LENGTH", 64),
+			RequireUpper:   config.GetenvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:   config.GetenvBool("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit:   config.GetenvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:  config.GetenvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			AllowUnicode:   config.GetenvBool("PASSWORD_ALLOW_UNICODE", true),
+			RejectUserInfo: config.GetenvBool("PASSWORD_REJECT_USER_INFO", true),
+			RejectCommon:   config.GetenvBool("PASSWORD_REJECT_COMMON", true),
+			History:        config.GetenvInt("PASSWORD_HISTORY", 0),
+			MaxAge:         config.GetenvDuration("PASSWORD_MAX_AGE", 0),
 		},
 	}
 
 	// Initialize datastore
-	if err := registry.InitDatastore(c.Debug, c.DB); err != nil {
+	db, err := registry.NewDatastore(c.Debug, c.DB)
+	if err != nil {
 		log.Fatal().Err(err)
 	}
 
 	// Initialize router
-	r, err := registry.NewRouter(c)
+	r, err := registry.NewRouter(c, db)
 	if err != nil {
 		log.Fatal().Err(err)
 	}
@@ -1,43 +1,743 @@
 package mock
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository struct {
 	User            *entity.User
 	IsMatchPassword bool
+	// Password given to Rehash at last
+	Rehashed string
+	// Current password is kept in it at UpdatePassword if not nil
+	Histories *PasswordHistoryRepository
 }
 
-func (r *UserRepository) Exists(account string) (bool, error) {
+func (r *UserRepository) Exists(ctx context.Context, account string) (bool, error) {
 	return r.User != nil, nil
 }
 
-func (r *UserRepository) Find(id uint) (*entity.User, error) {
+func (r *UserRepository) Find(ctx context.Context, id uint) (*entity.User, error) {
 	return r.User, nil
 }
 
-func (r *UserRepository) FindByAccount(account string) (*entity.User, error) {
+func (r *UserRepository) FindAll(ctx context.Context, f entity.UserFilter) ([]entity.User, int64, error) {
+	if r.User == nil {
+		return []entity.User{}, 0, nil
+	}
+	return []entity.User{*r.User}, 1, nil
+}
+
+func (r *UserRepository) FindByAccount(ctx context.Context, account string) (*entity.User, error) {
 	return r.User, nil
 }
 
-func (r *UserRepository) MatchPassword(hashedPassword, password string) error {
+func (r *UserRepository) FindByMailAddress(ctx context.Context, mailAddress string) ([]entity.User, error) {
+	if r.User == nil || r.User.MailAddress != mailAddress {
+		return []entity.User{}, nil
+	}
+	return []entity.User{*r.User}, nil
+}
+
+func (r *UserRepository) MatchPassword(ctx context.Context, hashedPassword, password string) error {
 	if r.IsMatchPassword {
 		return nil
 	}
-	return errors.New("password not matched")
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+func (r *UserRepository) Rehash(ctx context.Context, u *entity.User, password string) error {
+	r.Rehashed = password
+	return nil
 }
 
-func (r *UserRepository) Create(u *entity.User) (string, error) {
+func (r *UserRepository) Create(ctx context.Context, u *entity.User) (string, error) {
 	return "hogefuga", nil
 }
 
-func (r *UserRepository) UpdatePassword(u *entity.User, pass string) error {
+func (r *UserRepository) UpdatePassword(ctx context.Context, u *entity.User, pass string, histories int) error {
+	if r.Histories != nil && histories > 0 && u.Password != "" {
+		if err := r.Histories.Create(ctx, &entity.PasswordHistory{UserID: u.ID, Password: u.Password}); err != nil {
+			return err
+		}
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	u.Password = string(hashed)
+	u.PasswordChangedAt = &now
+	u.IsActive = true
+	return nil
+}
+
+func (r *UserRepository) UpdateAuthed(ctx context.Context, u *entity.User) error {
 	return nil
 }
 
-func (r *UserRepository) UpdateAuthed(u *entity.User) error {
+func (r *UserRepository) IncrementFailedLogins(ctx context.Context, u *entity.User) error {
+	u.FailedLogins++
+	return nil
+}
+
+func (r *UserRepository) Lock(ctx context.Context, u *entity.User, until time.Time) error {
+	u.LockedUntil = &until
+	return nil
+}
+
+func (r *UserRepository) Unlock(ctx context.Context, u *entity.User) error {
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	return nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, u *entity.User) error {
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, u *entity.User) error {
+	return nil
+}
+
+type RevokedTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func (r *RevokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiredAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens == nil {
+		r.tokens = map[string]time.Time{}
+	}
+	r.tokens[tokenID] = expiredAt
+	return nil
+}
+
+func (r *RevokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.tokens[tokenID]
+	return ok, nil
+}
+
+type RefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*entity.RefreshToken
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, t *entity.RefreshToken) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens == nil {
+		r.tokens = map[string]*entity.RefreshToken{}
+	}
+	token := fmt.Sprintf("refresh-token-%d", len(r.tokens)+1)
+	t.ID = uint(len(r.tokens) + 1)
+	t.TokenHash = token
+	r.tokens[token] = t
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) FindByToken(ctx context.Context, token string) (*entity.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tokens[token]; ok {
+		v := *t
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *RefreshTokenRepository) Use(ctx context.Context, t *entity.RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tokens[t.TokenHash]
+	if !ok || stored.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	return true, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeUser(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
 	return nil
 }
+
+type InvitationRepository struct {
+	mu          sync.Mutex
+	seq         uint
+	invitations map[string]*entity.Invitation
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, i *entity.Invitation) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.invitations == nil {
+		r.invitations = map[string]*entity.Invitation{}
+	}
+	r.seq++
+	code := fmt.Sprintf("invitation-code-%d", r.seq)
+	i.ID = r.seq
+	i.CodeHash = code
+	r.invitations[code] = i
+	return code, nil
+}
+
+func (r *InvitationRepository) Find(ctx context.Context, id uint) (*entity.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.invitations {
+		if i.ID == id {
+			v := *i
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InvitationRepository) FindAll(ctx context.Context) ([]entity.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []entity.Invitation{}
+	for _, i := range r.invitations {
+		res = append(res, *i)
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].ID < res[b].ID })
+	return res, nil
+}
+
+func (r *InvitationRepository) Use(ctx context.Context, code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.invitations[code]
+	if !ok || i.UsedAt != nil || !i.ExpiredAt.After(time.Now()) {
+		return false, nil
+	}
+	now := time.Now()
+	i.UsedAt = &now
+	return true, nil
+}
+
+func (r *InvitationRepository) Delete(ctx context.Context, i *entity.Invitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.invitations, i.CodeHash)
+	return nil
+}
+
+type APIKeyRepository struct {
+	mu   sync.Mutex
+	seq  uint
+	keys map[string]*entity.APIKey
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *entity.APIKey) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = map[string]*entity.APIKey{}
+	}
+	r.seq++
+	key := fmt.Sprintf("%sapi-key-%d", config.APIKeyPrefix, r.seq)
+	k.ID = r.seq
+	k.Prefix = key[:11]
+	k.KeyHash = key
+	r.keys[key] = k
+	return key, nil
+}
+
+func (r *APIKeyRepository) Find(ctx context.Context, id uint) (*entity.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.ID == id {
+			v := *k
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *APIKeyRepository) FindAll(ctx context.Context, userID uint) ([]entity.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []entity.APIKey{}
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			res = append(res, *k)
+		}
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].ID < res[b].ID })
+	return res, nil
+}
+
+func (r *APIKeyRepository) FindByKey(ctx context.Context, key string) (*entity.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.keys[key]; ok {
+		v := *k
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *APIKeyRepository) Touch(ctx context.Context, k *entity.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	k.LastUsedAt = &now
+	if stored, ok := r.keys[k.KeyHash]; ok {
+		stored.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) Delete(ctx context.Context, k *entity.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, k.KeyHash)
+	return nil
+}
+
+type SessionRepository struct {
+	mu       sync.Mutex
+	seq      uint
+	sessions map[uint]*entity.Session
+}
+
+func (r *SessionRepository) Create(ctx context.Context, s *entity.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions == nil {
+		r.sessions = map[uint]*entity.Session{}
+	}
+	r.seq++
+	s.ID = r.seq
+	s.CreatedAt = time.Now()
+	v := *s
+	r.sessions[s.ID] = &v
+	return nil
+}
+
+func (r *SessionRepository) Find(ctx context.Context, id uint) (*entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		v := *s
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *SessionRepository) FindByFamily(ctx context.Context, familyID string) (*entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.FamilyID == familyID {
+			v := *s
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *SessionRepository) FindAll(ctx context.Context, userID uint) ([]entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []entity.Session{}
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.Active(time.Now()) {
+			res = append(res, *s)
+		}
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].ID < res[b].ID })
+	return res, nil
+}
+
+func (r *SessionRepository) Update(ctx context.Context, s *entity.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := *s
+	r.sessions[s.ID] = &v
+	return nil
+}
+
+func (r *SessionRepository) Touch(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		now := time.Now()
+		s.LastSeenAt = &now
+	}
+	return nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, s *entity.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	s.RevokedAt = &now
+	if stored, ok := r.sessions[s.ID]; ok && stored.RevokedAt == nil {
+		stored.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeUser(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type PasswordResetRepository struct {
+	mu     sync.Mutex
+	resets map[string]*entity.PasswordReset
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, pr *entity.PasswordReset) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resets == nil {
+		r.resets = map[string]*entity.PasswordReset{}
+	}
+	token := fmt.Sprintf("reset-token-%d", len(r.resets)+1)
+	pr.ID = uint(len(r.resets) + 1)
+	pr.TokenHash = token
+	r.resets[token] = pr
+	return token, nil
+}
+
+func (r *PasswordResetRepository) FindByToken(ctx context.Context, token string) (*entity.PasswordReset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pr, ok := r.resets[token]; ok {
+		v := *pr
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *PasswordResetRepository) Use(ctx context.Context, pr *entity.PasswordReset) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.resets[pr.TokenHash]
+	if !ok || stored.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	return true, nil
+}
+
+type MailVerificationRepository struct {
+	mu            sync.Mutex
+	verifications map[string]*entity.MailVerification
+}
+
+func (r *MailVerificationRepository) Create(ctx context.Context, v *entity.MailVerification) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.verifications == nil {
+		r.verifications = map[string]*entity.MailVerification{}
+	}
+	token := fmt.Sprintf("verification-token-%d", len(r.verifications)+1)
+	v.ID = uint(len(r.verifications) + 1)
+	v.TokenHash = token
+	r.verifications[token] = v
+	return token, nil
+}
+
+func (r *MailVerificationRepository) FindByToken(ctx context.Context, token string) (*entity.MailVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.verifications[token]; ok {
+		c := *v
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (r *MailVerificationRepository) Use(ctx context.Context, v *entity.MailVerification) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.verifications[v.TokenHash]
+	if !ok || stored.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	return true, nil
+}
+
+type PasswordHistoryRepository struct {
+	mu        sync.Mutex
+	histories []entity.PasswordHistory
+}
+
+func (r *PasswordHistoryRepository) Create(ctx context.Context, h *entity.PasswordHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h.ID = uint(len(r.histories) + 1)
+	r.histories = append(r.histories, *h)
+	return nil
+}
+
+func (r *PasswordHistoryRepository) FindRecent(ctx context.Context, userID uint, limit int) ([]entity.PasswordHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []entity.PasswordHistory{}
+	for i := len(r.histories) - 1; i >= 0 && len(res) < limit; i-- {
+		if r.histories[i].UserID == userID {
+			res = append(res, r.histories[i])
+		}
+	}
+	return res, nil
+}
+
+type RecoveryCodeRepository struct {
+	mu    sync.Mutex
+	codes map[uint]map[string]bool
+}
+
+func (r *RecoveryCodeRepository) Create(ctx context.Context, userID uint, n int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codes == nil {
+		r.codes = map[uint]map[string]bool{}
+	}
+	codes := make([]string, n)
+	r.codes[userID] = map[string]bool{}
+	for i := range codes {
+		codes[i] = fmt.Sprintf("recovery-code-%d", i+1)
+		r.codes[userID][codes[i]] = false
+	}
+	return codes, nil
+}
+
+func (r *RecoveryCodeRepository) Use(ctx context.Context, userID uint, code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	used, ok := r.codes[userID][code]
+	if !ok || used {
+		return false, nil
+	}
+	r.codes[userID][code] = true
+	return true, nil
+}
+
+func (r *RecoveryCodeRepository) Delete(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.codes, userID)
+	return nil
+}
+
+type ClientRepository struct {
+	mu      sync.Mutex
+	seq     uint
+	clients map[uint]*entity.Client
+}
+
+func (r *ClientRepository) Create(ctx context.Context, c *entity.Client) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clients == nil {
+		r.clients = map[uint]*entity.Client{}
+	}
+	r.seq++
+	c.ID = r.seq
+	c.ClientID = fmt.Sprintf("client-%d", r.seq)
+	c.SecretHash = fmt.Sprintf("client-secret-%d", r.seq)
+	v := *c
+	r.clients[c.ID] = &v
+	return c.SecretHash, nil
+}
+
+func (r *ClientRepository) Find(ctx context.Context, id uint) (*entity.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clients[id]; ok {
+		v := *c
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *ClientRepository) FindByClientID(ctx context.Context, clientID string) (*entity.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.clients {
+		if c.ClientID == clientID {
+			v := *c
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *ClientRepository) FindAll(ctx context.Context) ([]entity.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := []entity.Client{}
+	for _, c := range r.clients {
+		res = append(res, *c)
+	}
+	sort.Slice(res, func(a, b int) bool { return res[a].ID < res[b].ID })
+	return res, nil
+}
+
+func (r *ClientRepository) MatchSecret(ctx context.Context, hashedSecret, secret string) error {
+	if hashedSecret != secret {
+		return errors.New("secret not matched")
+	}
+	return nil
+}
+
+func (r *ClientRepository) RegenerateSecret(ctx context.Context, c *entity.Client) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.SecretHash = c.SecretHash + "-regenerated"
+	if stored, ok := r.clients[c.ID]; ok {
+		stored.SecretHash = c.SecretHash
+	}
+	return c.SecretHash, nil
+}
+
+func (r *ClientRepository) Update(ctx context.Context, c *entity.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := *c
+	r.clients[c.ID] = &v
+	return nil
+}
+
+func (r *ClientRepository) Delete(ctx context.Context, c *entity.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, c.ID)
+	return nil
+}
+
+type AuthorizationCodeRepository struct {
+	mu    sync.Mutex
+	codes map[string]*entity.AuthorizationCode
+}
+
+func (r *AuthorizationCodeRepository) Create(ctx context.Context, ac *entity.AuthorizationCode) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codes == nil {
+		r.codes = map[string]*entity.AuthorizationCode{}
+	}
+	code := fmt.Sprintf("authorization-code-%d", len(r.codes)+1)
+	ac.ID = uint(len(r.codes) + 1)
+	ac.CodeHash = code
+	r.codes[code] = ac
+	return code, nil
+}
+
+func (r *AuthorizationCodeRepository) FindByCode(ctx context.Context, code string) (*entity.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ac, ok := r.codes[code]; ok {
+		v := *ac
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (r *AuthorizationCodeRepository) Use(ctx context.Context, ac *entity.AuthorizationCode) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.codes[ac.CodeHash]
+	if !ok || stored.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	return true, nil
+}
+
+type AuditLogRepository struct {
+	mu     sync.Mutex
+	Events []entity.AuditEvent
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, e *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.ID = uint(len(r.Events) + 1)
+	e.CreatedAt = time.Now()
+	r.Events = append(r.Events, *e)
+	return nil
+}
+
+func (r *AuditLogRepository) FindAll(ctx context.Context, f entity.AuditFilter) ([]entity.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := []entity.AuditEvent{}
+	for i := len(r.Events) - 1; i >= 0 && len(events) < f.Limit; i-- {
+		e := r.Events[i]
+		if f.Cursor > 0 && e.ID >= f.Cursor {
+			continue
+		}
+		if f.Type != nil && e.Type != *f.Type {
+			continue
+		}
+		if f.Account != nil && e.Account != *f.Account {
+			continue
+		}
+		if f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID) {
+			continue
+		}
+		if f.TargetID != nil && (e.TargetID == nil || *e.TargetID != *f.TargetID) {
+			continue
+		}
+		if f.Since != nil && e.CreatedAt.Before(*f.Since) {
+			continue
+		}
+		if f.Until != nil && !e.CreatedAt.Before(*f.Until) {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Mailer struct {
+	mu    sync.Mutex
+	Mails []entity.Mail
+}
+
+func (m *Mailer) Send(e entity.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Mails = append(m.Mails, e)
+	return nil
+}
+
+type PasswordHasher struct {
+	// Maximum bytes of password, 0 is unlimited
+	Max int
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(hashed), err
+}
+
+func (h *PasswordHasher) Verify(hash, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+func (h *PasswordHasher) MaxLength() int {
+	return h.Max
+}
+
+type RateLimiter struct {
+	mu    sync.Mutex
+	taken map[string]int
+}
+
+func (l *RateLimiter) Take(key string, burst int, period time.Duration) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.taken == nil {
+		l.taken = map[string]int{}
+	}
+	if l.taken[key] >= burst {
+		return false, period / time.Duration(burst), nil
+	}
+	l.taken[key]++
+	return true, 0, nil
+}
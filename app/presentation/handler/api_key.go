@@ -0,0 +1,10 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// APIKey is action handler about personal API key of authenticated user
+type APIKey interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Delete(c *gin.Context)
+}
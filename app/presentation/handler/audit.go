@@ -0,0 +1,8 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Audit is action handler about audit log for administrator
+type Audit interface {
+	List(c *gin.Context)
+}
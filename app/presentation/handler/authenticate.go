@@ -5,6 +5,18 @@ import "github.com/gin-gonic/gin"
 // User is action handler about user data
 type User interface {
 	Register(c *gin.Context)
-	Activate(c *gin.Context)
 	Identity(c *gin.Context)
 }
+
+// AdminUser is action handler about user management for administrator
+type AdminUser interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Update(c *gin.Context)
+	Enable(c *gin.Context)
+	Disable(c *gin.Context)
+	Unlock(c *gin.Context)
+	ExpirePassword(c *gin.Context)
+	ResetMFA(c *gin.Context)
+	Delete(c *gin.Context)
+}
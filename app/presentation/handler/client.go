@@ -0,0 +1,13 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Client is action handler about OAuth client management for administrator
+type Client interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	RegenerateSecret(c *gin.Context)
+	Delete(c *gin.Context)
+}
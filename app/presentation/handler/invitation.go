@@ -0,0 +1,10 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Invitation is action handler about invitation code for registration
+type Invitation interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Delete(c *gin.Context)
+}
@@ -0,0 +1,8 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Key is action handler for token signing keys
+type Key interface {
+	JWKS(c *gin.Context)
+}
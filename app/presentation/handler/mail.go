@@ -0,0 +1,9 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Mail is action handler about verifying mail address
+type Mail interface {
+	Verify(c *gin.Context)
+	Resend(c *gin.Context)
+}
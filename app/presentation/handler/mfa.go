@@ -0,0 +1,10 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// MFA is action handler about two-factor authentication of authenticated user
+type MFA interface {
+	Enroll(c *gin.Context)
+	Confirm(c *gin.Context)
+	Disable(c *gin.Context)
+}
@@ -0,0 +1,21 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// OAuth is action handler for OAuth authorization server
+type OAuth interface {
+	Authorize(c *gin.Context)
+	Consent(c *gin.Context)
+	Token(c *gin.Context)
+}
+
+// Introspect is action handler for token introspection of resource server
+type Introspect interface {
+	Introspect(c *gin.Context)
+}
+
+// OIDC is action handler for OpenID Provider
+type OIDC interface {
+	Configuration(c *gin.Context)
+	UserInfo(c *gin.Context)
+}
@@ -0,0 +1,9 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Password is action handler about resetting forgotten password
+type Password interface {
+	Forgot(c *gin.Context)
+	Reset(c *gin.Context)
+}
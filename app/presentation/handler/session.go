@@ -0,0 +1,10 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// Session is action handler about signed in session of authenticated user
+type Session interface {
+	List(c *gin.Context)
+	Delete(c *gin.Context)
+	DeleteOthers(c *gin.Context)
+}
@@ -1,8 +1,27 @@
 package middleware
 
-import jwt "github.com/appleboy/gin-jwt/v2"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+)
+
+// JWT is handlers for issuing and verifying token
+type JWT interface {
+	MiddlewareFunc() gin.HandlerFunc
+	PasswordChangeFunc() gin.HandlerFunc
+	LoginHandler(c *gin.Context)
+	MFAHandler(c *gin.Context)
+	RefreshHandler(c *gin.Context)
+	TokenRefreshHandler(c *gin.Context)
+	ActivateHandler(c *gin.Context)
+	ChangePasswordHandler(c *gin.Context)
+	LogoutHandler(c *gin.Context)
+	RequireRole(roles ...entity.Role) gin.HandlerFunc
+	RequireScope(scope string) gin.HandlerFunc
+	RequireSession() gin.HandlerFunc
+}
 
 // Auth is middleware interface for authentication and authorization
 type Auth interface {
-	Create() (*jwt.GinJWTMiddleware, error)
+	Create() (JWT, error)
 }
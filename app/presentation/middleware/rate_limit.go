@@ -0,0 +1,11 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gotoeveryone/auth-api/app/config"
+)
+
+// RateLimit is middleware interface for limiting requests
+type RateLimit interface {
+	Limit(name string, l config.RateLimit) gin.HandlerFunc
+}
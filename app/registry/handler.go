@@ -1,7 +1,9 @@
 package registry
 
 import (
+	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
 	"github.com/gotoeveryone/auth-api/app/interface/api/server"
 	"github.com/gotoeveryone/auth-api/app/presentation/handler"
 )
@@ -12,6 +14,71 @@ func NewStateHandler() handler.State {
 }
 
 // NewUserHandler is create action handler for user
-func NewUserHandler(r repository.User) handler.User {
-	return server.NewUserHandler(r)
+func NewUserHandler(r repository.User, ir repository.Invitation, vr repository.MailVerification, al repository.AuditLog, m service.Mailer, registration string) handler.User {
+	return server.NewUserHandler(r, ir, vr, al, m, registration)
+}
+
+// NewPasswordHandler is create action handler for resetting password
+func NewPasswordHandler(r repository.User, pr repository.PasswordReset, tr repository.RefreshToken, sr repository.Session, al repository.AuditLog, pp *server.PasswordPolicy, m service.Mailer) handler.Password {
+	return server.NewPasswordHandler(r, pr, tr, sr, al, pp, m)
+}
+
+// NewMailHandler is create action handler for verifying mail address
+func NewMailHandler(r repository.User, vr repository.MailVerification, m service.Mailer) handler.Mail {
+	return server.NewMailHandler(r, vr, m)
+}
+
+// NewAdminUserHandler is create action handler for user management
+func NewAdminUserHandler(r repository.User, rc repository.RecoveryCode, vr repository.MailVerification, tr repository.RefreshToken, sr repository.Session, al repository.AuditLog, m service.Mailer) handler.AdminUser {
+	return server.NewAdminUserHandler(r, rc, vr, tr, sr, al, m)
+}
+
+// NewMFAHandler is create action handler for two-factor authentication
+func NewMFAHandler(r repository.User, rc repository.RecoveryCode, issuer string) handler.MFA {
+	return server.NewMFAHandler(r, rc, issuer)
+}
+
+// NewInvitationHandler is create action handler for invitation code
+func NewInvitationHandler(r repository.Invitation) handler.Invitation {
+	return server.NewInvitationHandler(r)
+}
+
+// NewClientHandler is create action handler for OAuth client management
+func NewClientHandler(r repository.Client) handler.Client {
+	return server.NewClientHandler(r)
+}
+
+// NewAPIKeyHandler is create action handler for personal API key
+func NewAPIKeyHandler(r repository.APIKey) handler.APIKey {
+	return server.NewAPIKeyHandler(r)
+}
+
+// NewSessionHandler is create action handler for signed in session
+func NewSessionHandler(r repository.Session, tr repository.RefreshToken) handler.Session {
+	return server.NewSessionHandler(r, tr)
+}
+
+// NewAuditHandler is create action handler for audit log
+func NewAuditHandler(r repository.AuditLog) handler.Audit {
+	return server.NewAuditHandler(r)
+}
+
+// NewKeyHandler is create action handler for token signing keys
+func NewKeyHandler(ks *server.KeySet) handler.Key {
+	return server.NewKeyHandler(ks)
+}
+
+// NewOAuthHandler is create action handler for OAuth authorization server
+func NewOAuthHandler(d server.AuthDeps, cr repository.Client, ar repository.AuthorizationCode) (handler.OAuth, error) {
+	return server.NewOAuthHandler(d, cr, ar)
+}
+
+// NewIntrospectHandler is create action handler for token introspection
+func NewIntrospectHandler(d server.AuthDeps, cr repository.Client) (handler.Introspect, error) {
+	return server.NewIntrospectHandler(d, cr)
+}
+
+// NewOIDCHandler is create action handler for OpenID Provider
+func NewOIDCHandler(c config.JWT, ks *server.KeySet) handler.OIDC {
+	return server.NewOIDCHandler(c, ks)
 }
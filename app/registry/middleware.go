@@ -1,12 +1,29 @@
 package registry
 
 import (
+	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
 	"github.com/gotoeveryone/auth-api/app/interface/api/server"
 	"github.com/gotoeveryone/auth-api/app/presentation/middleware"
 )
 
+// NewKeySet is create key set for signing and verifying token
+func NewKeySet(c config.JWT) (*server.KeySet, error) {
+	return server.NewKeySet(c)
+}
+
+// NewPasswordPolicy is create policy for validating new password
+func NewPasswordPolicy(c config.PasswordPolicy, ur repository.User, hr repository.PasswordHistory, h service.PasswordHasher) *server.PasswordPolicy {
+	return server.NewPasswordPolicy(c, ur, hr, h)
+}
+
 // NewAuthMiddleware is create middleware about auth
-func NewAuthMiddleware(ur repository.User) middleware.Auth {
-	return server.NewAuthMiddleware(ur)
+func NewAuthMiddleware(d server.AuthDeps) middleware.Auth {
+	return server.NewAuthMiddleware(d)
+}
+
+// NewRateLimitMiddleware is create middleware for limiting requests
+func NewRateLimitMiddleware(l service.RateLimiter) middleware.RateLimit {
+	return server.NewRateLimitMiddleware(l)
 }
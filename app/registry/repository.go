@@ -2,10 +2,72 @@ package registry
 
 import (
 	"github.com/gotoeveryone/auth-api/app/domain/repository"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
 	"github.com/gotoeveryone/auth-api/app/infrastructure/database"
+	"gorm.io/gorm"
 )
 
 // NewUserRepository is create user management repository.
-func NewUserRepository() repository.User {
-	return database.NewUserRepository()
+func NewUserRepository(db *gorm.DB, h service.PasswordHasher) repository.User {
+	return database.NewUserRepository(db, h)
+}
+
+// NewRevokedTokenRepository is create revoked token management repository.
+func NewRevokedTokenRepository(db *gorm.DB) repository.RevokedToken {
+	return database.NewRevokedTokenRepository(db)
+}
+
+// NewRefreshTokenRepository is create refresh token management repository.
+func NewRefreshTokenRepository(db *gorm.DB) repository.RefreshToken {
+	return database.NewRefreshTokenRepository(db)
+}
+
+// NewInvitationRepository is create invitation code management repository.
+func NewInvitationRepository(db *gorm.DB) repository.Invitation {
+	return database.NewInvitationRepository(db)
+}
+
+// NewPasswordResetRepository is create password reset token management repository.
+func NewPasswordResetRepository(db *gorm.DB) repository.PasswordReset {
+	return database.NewPasswordResetRepository(db)
+}
+
+// NewMailVerificationRepository is create mail verification token management repository.
+func NewMailVerificationRepository(db *gorm.DB) repository.MailVerification {
+	return database.NewMailVerificationRepository(db)
+}
+
+// NewAuditLogRepository is create audit event management repository.
+func NewAuditLogRepository(db *gorm.DB) repository.AuditLog {
+	return database.NewAuditLogRepository(db)
+}
+
+// NewRecoveryCodeRepository is create recovery code management repository.
+func NewRecoveryCodeRepository(db *gorm.DB) repository.RecoveryCode {
+	return database.NewRecoveryCodeRepository(db)
+}
+
+// NewClientRepository is create OAuth client management repository.
+func NewClientRepository(db *gorm.DB) repository.Client {
+	return database.NewClientRepository(db)
+}
+
+// NewAuthorizationCodeRepository is create authorization code management repository.
+func NewAuthorizationCodeRepository(db *gorm.DB) repository.AuthorizationCode {
+	return database.NewAuthorizationCodeRepository(db)
+}
+
+// NewAPIKeyRepository is create personal API key management repository.
+func NewAPIKeyRepository(db *gorm.DB) repository.APIKey {
+	return database.NewAPIKeyRepository(db)
+}
+
+// NewSessionRepository is create signed in session management repository.
+func NewSessionRepository(db *gorm.DB) repository.Session {
+	return database.NewSessionRepository(db)
+}
+
+// NewPasswordHistoryRepository is create previous password management repository.
+func NewPasswordHistoryRepository(db *gorm.DB) repository.PasswordHistory {
+	return database.NewPasswordHistoryRepository(db)
 }
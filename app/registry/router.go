@@ -1,29 +1,113 @@
 package registry
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/entity"
+	"github.com/gotoeveryone/auth-api/app/interface/api/server"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
-func NewRouter(config config.App) (*gin.Engine, error) {
+func NewRouter(config config.App, db *gorm.DB) (*gin.Engine, error) {
+	if !config.ValidRegistration() {
+		return nil, fmt.Errorf("unknown registration mode: %s", config.Registration)
+	}
+	if !config.ValidOAuth() {
+		return nil, errors.New("issuer of JWT is required when OAuth is enabled")
+	}
+
 	// Initialize application
 	r := gin.Default()
 	r.HandleMethodNotAllowed = true
+	// Client IP is used for rate limit, so X-Forwarded-For is not trusted unless it is set by known proxy
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// Password hashing
+	hs, err := NewPasswordHasher(config.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
 
 	// Repository
-	ur := NewUserRepository()
+	ur := NewUserRepository(db, hs)
+	rr := NewRevokedTokenRepository(db)
+	tr := NewRefreshTokenRepository(db)
+	ir := NewInvitationRepository(db)
+	pr := NewPasswordResetRepository(db)
+	vr := NewMailVerificationRepository(db)
+	cr := NewRecoveryCodeRepository(db)
+	oc := NewClientRepository(db)
+	ac := NewAuthorizationCodeRepository(db)
+	kr := NewAPIKeyRepository(db)
+	sr := NewSessionRepository(db)
+	al := NewAuditLogRepository(db)
+	hr := NewPasswordHistoryRepository(db)
+
+	// Service
+	ms, err := NewMailer(config.Mail)
+	if err != nil {
+		return nil, err
+	}
+	ls := NewRateLimiter()
+	pp := NewPasswordPolicy(config.PasswordPolicy, ur, hr, hs)
+
+	// Signing keys
+	ks, err := NewKeySet(config.JWT)
+	if err != nil {
+		return nil, err
+	}
 
 	// Handler
 	sh := NewStateHandler()
-	uh := NewUserHandler(ur)
+	uh := NewUserHandler(ur, ir, vr, al, ms, config.Registration)
+	ph := NewPasswordHandler(ur, pr, tr, sr, al, pp, ms)
+	vh := NewMailHandler(ur, vr, ms)
+	ah := NewAdminUserHandler(ur, cr, vr, tr, sr, al, ms)
+	mh := NewMFAHandler(ur, cr, config.MFAIssuer)
+	ch := NewClientHandler(oc)
+	ih := NewInvitationHandler(ir)
+	akh := NewAPIKeyHandler(kr)
+	ssh := NewSessionHandler(sr, tr)
+	auh := NewAuditHandler(al)
+	kh := NewKeyHandler(ks)
 
 	// Middleware
-	m, err := NewAuthMiddleware(ur).Create()
+	deps := server.AuthDeps{
+		Config:              config.JWT,
+		Lockout:             config.Lockout,
+		Keys:                ks,
+		Users:               ur,
+		Revoked:             rr,
+		Refresh:             tr,
+		Recovery:            cr,
+		APIKeys:             kr,
+		Sessions:            sr,
+		Clients:             oc,
+		Audit:               al,
+		Policy:              pp,
+		RequireVerifiedMail: config.RequireMailVerification,
+	}
+	m, err := NewAuthMiddleware(deps).Create()
+	if err != nil {
+		return nil, err
+	}
+	oh, err := NewOAuthHandler(deps, oc, ac)
 	if err != nil {
 		return nil, err
 	}
+	oih, err := NewIntrospectHandler(deps, oc)
+	if err != nil {
+		return nil, err
+	}
+	odh := NewOIDCHandler(config.JWT, ks)
+	rl := NewRateLimitMiddleware(ls)
 
 	// Routing
 	// Root
@@ -32,20 +116,87 @@ func NewRouter(config config.App) (*gin.Engine, error) {
 	r.NoRoute(sh.NoRoute)
 	// Method Not Allowed
 	r.NoMethod(sh.NoMethod)
+	// Public keys
+	r.GET("/.well-known/jwks.json", kh.JWKS)
+	// OAuth
+	if config.OAuth {
+		r.GET("/.well-known/openid-configuration", odh.Configuration)
+		r.GET("/oauth/authorize", oh.Authorize)
+		r.POST("/oauth/authorize", rl.Limit("authorize", config.RateLimits.Auth), oh.Consent)
+		r.POST("/oauth/token", rl.Limit("token", config.RateLimits.Auth), oh.Token)
+		r.POST("/oauth/introspect", rl.Limit("introspect", config.RateLimits.Introspect), oih.Introspect)
+		r.GET("/oauth/userinfo", m.MiddlewareFunc(), odh.UserInfo)
+		r.POST("/oauth/userinfo", m.MiddlewareFunc(), odh.UserInfo)
+	}
 	// Application
 	v1 := r.Group("v1")
 	{
 		v1.GET("/", sh.Get)
-		v1.POST("/users", uh.Register)
-		v1.POST("/activate", uh.Activate)
-		v1.POST("/auth", m.LoginHandler)
+		if config.PublicRegistration() {
+			v1.POST("/users", rl.Limit("register", config.RateLimits.Register), uh.Register)
+		}
+		v1.POST("/activate", rl.Limit("activate", config.RateLimits.Activate), m.ActivateHandler)
+		v1.POST("/password/forgot", rl.Limit("forgot", config.RateLimits.Password), ph.Forgot)
+		v1.POST("/password/reset", rl.Limit("reset", config.RateLimits.Password), ph.Reset)
+		v1.POST("/mail/verify", rl.Limit("verify", config.RateLimits.MailVerify), vh.Verify)
+		v1.POST("/mail/verify/resend", rl.Limit("resend", config.RateLimits.MailVerify), vh.Resend)
+		v1.POST("/auth", rl.Limit("auth", config.RateLimits.Auth), m.LoginHandler)
+		v1.POST("/auth/mfa", rl.Limit("mfa", config.RateLimits.Auth), m.MFAHandler)
 		v1.GET("/refresh_token", m.RefreshHandler)
+		v1.POST("/token/refresh", rl.Limit("refresh", config.RateLimits.Refresh), m.TokenRefreshHandler)
+		// Token only for changing password issued at login of the user who must change it is also accepted
+		v1.PUT("/me/password", m.PasswordChangeFunc(), m.RequireSession(), m.ChangePasswordHandler)
 		auth := v1.Group("")
 		{
 			auth.Use(m.MiddlewareFunc())
 			{
-				auth.GET("/me", uh.Identity)
-				auth.DELETE("/deauth", m.LogoutHandler)
+				auth.GET("/me", m.RequireScope(entity.ScopeProfile), uh.Identity)
+				if !config.PublicRegistration() {
+					auth.POST("/users", rl.Limit("register", config.RateLimits.Register), m.RequireScope(entity.ScopeAdmin), m.RequireRole(entity.RoleAdministrator), uh.Register)
+				}
+			}
+			// Credentials of the user are managed only with token issued by login
+			session := auth.Group("")
+			{
+				session.Use(m.RequireSession())
+				{
+					session.POST("/me/mfa", mh.Enroll)
+					session.POST("/me/mfa/confirm", mh.Confirm)
+					session.DELETE("/me/mfa", mh.Disable)
+					session.GET("/me/api-keys", akh.List)
+					session.POST("/me/api-keys", akh.Create)
+					session.DELETE("/me/api-keys/:id", akh.Delete)
+					session.GET("/me/sessions", ssh.List)
+					session.DELETE("/me/sessions", ssh.DeleteOthers)
+					session.DELETE("/me/sessions/:id", ssh.Delete)
+					session.DELETE("/deauth", m.LogoutHandler)
+				}
+			}
+			admin := auth.Group("/admin")
+			{
+				admin.Use(m.RequireScope(entity.ScopeAdmin), m.RequireRole(entity.RoleAdministrator))
+				{
+					admin.GET("/users", ah.List)
+					admin.POST("/users", uh.Register)
+					admin.GET("/users/:id", ah.Get)
+					admin.PATCH("/users/:id", ah.Update)
+					admin.DELETE("/users/:id", ah.Delete)
+					admin.POST("/users/:id/enable", ah.Enable)
+					admin.POST("/users/:id/disable", ah.Disable)
+					admin.POST("/users/:id/unlock", ah.Unlock)
+					admin.POST("/users/:id/password/expire", ah.ExpirePassword)
+					admin.DELETE("/users/:id/mfa", ah.ResetMFA)
+					admin.GET("/invitations", ih.List)
+					admin.POST("/invitations", ih.Create)
+					admin.DELETE("/invitations/:id", ih.Delete)
+					admin.GET("/clients", ch.List)
+					admin.POST("/clients", ch.Create)
+					admin.GET("/clients/:id", ch.Get)
+					admin.PATCH("/clients/:id", ch.Update)
+					admin.DELETE("/clients/:id", ch.Delete)
+					admin.POST("/clients/:id/secret", ch.RegenerateSecret)
+					admin.GET("/audit", auh.List)
+				}
 			}
 		}
 	}
@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gotoeveryone/auth-api/app/config"
+	"github.com/gotoeveryone/auth-api/app/domain/service"
+	"github.com/gotoeveryone/auth-api/app/infrastructure/hasher"
+	"github.com/gotoeveryone/auth-api/app/infrastructure/mail"
+	"github.com/gotoeveryone/auth-api/app/infrastructure/ratelimit"
+)
+
+// NewMailer is create mailer with configured driver.
+// Mail is not delivered only if log driver is chosen explicitly, because it includes tokens for taking over account.
+func NewMailer(c config.Mail) (service.Mailer, error) {
+	switch c.Driver {
+	case config.MailDriverSMTP:
+		if c.Host == "" {
+			return nil, errors.New("SMTP server is required for smtp mail driver")
+		}
+		return mail.NewSMTPMailer(c), nil
+	case config.MailDriverLog:
+		return mail.NewLogMailer(c), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver: %s", c.Driver)
+}
+
+// NewPasswordHasher is create password hasher with configured algorithm
+func NewPasswordHasher(c config.PasswordHash) (service.PasswordHasher, error) {
+	return hasher.NewHasher(c)
+}
+
+// NewRateLimiter is create rate limiter keeping buckets in memory
+func NewRateLimiter() service.RateLimiter {
+	return ratelimit.NewMemoryRateLimiter()
+}
@@ -3,9 +3,10 @@ package registry
 import (
 	"github.com/gotoeveryone/auth-api/app/config"
 	"github.com/gotoeveryone/auth-api/app/infrastructure/database"
+	"gorm.io/gorm"
 )
 
-// InitDatastore is initialize datastore
-func InitDatastore(debug bool, db config.DB) error {
-	return database.Init(debug, db)
+// NewDatastore is connect to datastore, the connection is shared by repositories
+func NewDatastore(debug bool, db config.DB) (*gorm.DB, error) {
+	return database.Open(debug, db)
 }